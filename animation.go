@@ -0,0 +1,109 @@
+// Copyright 2022 Siôn le Roux.  All rights reserved.
+// Use of this source code is subject to an MIT-style
+// licence which can be found in the LICENSE file.
+
+package main
+
+import "log"
+
+// How much time passes in one tick of game logic, in milliseconds
+const tickMillis = 1000.0 / LogicTPS
+
+// Animation plays a tagged range of frames from a sprite sheet, showing each
+// frame for as long as its duration says
+type Animation struct {
+	Frame     int      // The frame currently being shown
+	Tag       FrameTag // The range of frames being played
+	Loop      bool     // Whether to start over after the last frame
+	Backwards bool     // Whether the frames are being played from last to first
+	elapsed   float64  // Milliseconds since the frame last changed
+}
+
+// NewAnimation starts playing a range of frames from the frame its direction
+// says to start from
+func NewAnimation(tag FrameTag, loop bool) Animation {
+	return Animation{
+		Frame:     tag.Start(),
+		Tag:       tag,
+		Loop:      loop,
+		Backwards: tag.Direction == tagReverse,
+	}
+}
+
+// Play switches to playing a different range of frames from the start, unless
+// that range is already playing
+func (a *Animation) Play(tag FrameTag, loop bool) {
+	a.Loop = loop
+	if a.Tag == tag {
+		return
+	}
+	*a = NewAnimation(tag, loop)
+}
+
+// Step advances the animation by one tick, moving on to the next frame once
+// the current one has been shown for its duration
+func (a *Animation) Step(frames Frames) {
+	if len(frames) == 0 {
+		return
+	}
+	a.keepInside(frames)
+	a.elapsed += tickMillis
+	if a.elapsed < float64(frames[a.Frame].Duration) {
+		return
+	}
+	a.elapsed -= float64(frames[a.Frame].Duration)
+	a.Frame, a.Backwards = a.Tag.Next(a.Frame, a.Backwards, a.Loop)
+	a.keepInside(frames)
+}
+
+// Start over from the start of the tag if it runs past the frames the sprite
+// has, or from the nearest frame it does have if even that's past them
+func (a *Animation) keepInside(frames Frames) {
+	if a.Frame >= 0 && a.Frame < len(frames) {
+		return
+	}
+	a.Frame = max(0, min(a.Tag.Start(), len(frames)-1))
+}
+
+// CurrentFrame returns the index of the sprite sheet frame to draw
+func (a *Animation) CurrentFrame() int {
+	return a.Frame
+}
+
+// Finished says whether a non-looping animation has reached its last frame,
+// which for ping-pong tags is the first one again on the way back
+func (a *Animation) Finished() bool {
+	if a.Loop {
+		return false
+	}
+	switch a.Tag.Direction {
+	case tagReverse:
+		return a.Frame == a.Tag.From
+	case tagPingPong:
+		return a.Frame == a.Tag.From && (a.Backwards || a.Tag.From == a.Tag.To)
+	default:
+		return a.Frame == a.Tag.To
+	}
+}
+
+// Sprites that have already been warned about asking for a frame they don't
+// have, so the log isn't flooded every frame
+var warnedFrames = map[*SpriteSheet]bool{}
+
+// Keep a frame index inside the frames a sprite actually has, in case its tags
+// say it has more than it does, warning the first time it happens, and say
+// whether there's any frame to draw at all
+func clampFrame(s *SpriteSheet, frame int) (int, bool) {
+	if frame >= 0 && frame < len(s.Sprite) {
+		return frame, true
+	}
+	if !warnedFrames[s] {
+		log.Printf("warning: frame %d is outside the %d frames of sprite %s\n",
+			frame, len(s.Sprite), s.Meta.ImageName)
+		warnedFrames[s] = true
+	}
+	if len(s.Sprite) == 0 {
+		return 0, false
+	}
+	return max(0, min(frame, len(s.Sprite)-1)), true
+}
@@ -0,0 +1,121 @@
+// Copyright 2022 Siôn le Roux.  All rights reserved.
+// Use of this source code is subject to an MIT-style
+// licence which can be found in the LICENSE file.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+func TestDrawShortSprite(t *testing.T) {
+	tests := []struct {
+		name   string
+		frames int
+	}{
+		{"no frames", 0},
+		{"fewer frames than its tags", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := newTestGame()
+			screen := ebiten.NewImage(GameSize.X, GameSize.Y)
+
+			s := newTestSprite()
+			s.Sprite = s.Sprite[:tt.frames]
+
+			tower := NewBasicTower(g)
+			tower.Sprite = s
+			tower.Animation = NewAnimation(s.Meta.FrameTags[2], true)
+			tower.Draw(g, screen)
+
+			creep := NewSmallCreep(g)
+			creep.Sprite = s
+			creep.Animation = NewAnimation(s.Meta.FrameTags[4], true)
+			creep.Draw(g, screen)
+		})
+	}
+}
+
+// Frames lasting a given number of milliseconds each
+func framesLasting(durations ...int) Frames {
+	frames := make(Frames, len(durations))
+	for i, d := range durations {
+		frames[i] = Frame{Duration: d}
+	}
+	return frames
+}
+
+func TestAnimationFrameDurations(t *testing.T) {
+	frames := framesLasting(90, 270, 45)
+	a := NewAnimation(FrameTag{From: 0, To: 2, Direction: tagForward}, true)
+
+	// Each frame is shown until its duration has passed, with any time left
+	// over from the tick it changed on counting towards the next one
+	for _, want := range []struct{ frame, ticks int }{{0, 6}, {1, 16}, {2, 3}} {
+		ticks := 0
+		for a.CurrentFrame() == want.frame && ticks < 100 {
+			a.Step(frames)
+			ticks++
+		}
+		if ticks != want.ticks {
+			t.Errorf("frame %d shown for %d ticks, want %d", want.frame, ticks, want.ticks)
+		}
+	}
+	if a.CurrentFrame() != 0 {
+		t.Errorf("looped to frame %d, want 0", a.CurrentFrame())
+	}
+}
+
+func TestAnimationPastLastFrame(t *testing.T) {
+	frames := framesLasting(10, 10)
+	a := NewAnimation(FrameTag{From: 1, To: 4, Direction: tagForward}, true)
+	a.Frame = 3
+
+	seen := map[int]bool{}
+	for i := 0; i < 20; i++ {
+		a.Step(frames)
+		if a.CurrentFrame() >= len(frames) {
+			t.Fatalf("stepped on to frame %d of %d", a.CurrentFrame(), len(frames))
+		}
+		seen[a.CurrentFrame()] = true
+	}
+	if !seen[1] {
+		t.Errorf("never showed frame 1, the start of the tag")
+	}
+
+	a.Step(nil) // Nothing to step through, but it shouldn't panic
+}
+
+func TestAnimationFinished(t *testing.T) {
+	frames := framesLasting(1, 1, 1, 1)
+	tests := []struct {
+		dir   string
+		steps int // Steps it takes to finish
+	}{
+		{tagForward, 2},
+		{tagReverse, 2},
+		{tagPingPong, 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.dir, func(t *testing.T) {
+			a := NewAnimation(FrameTag{From: 1, To: 3, Direction: tt.dir}, false)
+			for i := 0; i < tt.steps; i++ {
+				if a.Finished() {
+					t.Fatalf("finished on frame %d after %d steps, want %d", a.Frame, i, tt.steps)
+				}
+				a.Step(frames)
+			}
+			if !a.Finished() {
+				t.Errorf("not finished on frame %d after %d steps", a.Frame, tt.steps)
+			}
+
+			a.Loop = true
+			if a.Finished() {
+				t.Error("looping animation finished, want it never to")
+			}
+		})
+	}
+}
@@ -0,0 +1,48 @@
+// Copyright 2022 Siôn le Roux.  All rights reserved.
+// Use of this source code is subject to an MIT-style
+// licence which can be found in the LICENSE file.
+
+package main
+
+import "image"
+
+// How close the cursor can get to the edge of the screen before the camera
+// starts panning along with it
+const cameraMargin = 7
+
+// MapSize is the size of the current map in pixels, which may be bigger than
+// the screen
+func (g *Game) MapSize() image.Point {
+	if g.MapIndex < len(g.Maps) && g.Maps[g.MapIndex] != nil {
+		return g.Maps[g.MapIndex].Bounds().Size()
+	}
+	return g.Size
+}
+
+// ScreenCoords converts coordinates on the map to coordinates on the screen
+// using the camera position
+func (g *Game) ScreenCoords(world image.Point) image.Point {
+	return world.Sub(g.Camera)
+}
+
+// Pan the camera to keep the cursor on screen without going past the edges of
+// the map, so maps that are the same size as the screen never move
+func (g *Game) followCursor() {
+	c := g.Cursor.Coords
+	if c.X < g.Camera.X+cameraMargin {
+		g.Camera.X = c.X - cameraMargin
+	}
+	if c.X > g.Camera.X+g.Size.X-cameraMargin {
+		g.Camera.X = c.X - g.Size.X + cameraMargin
+	}
+	if c.Y < g.Camera.Y+hudHeight+cameraMargin {
+		g.Camera.Y = c.Y - hudHeight - cameraMargin
+	}
+	if c.Y > g.Camera.Y+g.Size.Y-cameraMargin {
+		g.Camera.Y = c.Y - g.Size.Y + cameraMargin
+	}
+
+	limit := g.MapSize().Sub(g.Size)
+	g.Camera.X = max(0, min(g.Camera.X, limit.X))
+	g.Camera.Y = max(0, min(g.Camera.Y, limit.Y))
+}
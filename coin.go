@@ -0,0 +1,58 @@
+// Copyright 2022 Siôn le Roux.  All rights reserved.
+// Use of this source code is subject to an MIT-style
+// licence which can be found in the LICENSE file.
+
+package main
+
+import (
+	"image"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// How many ticks a coin takes to fly from a dead creep up to the money counter
+const CoinFlightTicks = 20
+
+// Coin pops out of a creep when it dies and flies up to the money counter in
+// the HUD, it's only for show since the loot is paid straight away
+type Coin struct {
+	From image.Point // Where the creep died, on the screen
+	Age  int
+}
+
+// Coins is a slice of Coin entities
+type Coins []*Coin
+
+// Drop a coin where a creep died
+func (g *Game) dropCoin(c *Creep) {
+	g.Coins = append(g.Coins, &Coin{From: g.ScreenCoords(c.Coords)})
+}
+
+// Move every coin on and forget the ones that have reached the HUD
+func (g *Game) updateCoins() {
+	var flying Coins
+	for _, c := range g.Coins {
+		c.Age++
+		if c.Age < CoinFlightTicks {
+			flying = append(flying, c)
+		}
+	}
+	g.Coins = flying
+}
+
+// Draw the coin part of the way from where it was dropped to the money icon,
+// in light once it's over the HUD so it doesn't vanish into it
+func (c *Coin) Draw(g *Game, screen *ebiten.Image) {
+	img := iconImage(g.Sprites[spriteIconMoney])
+	size := img.Bounds().Size()
+	to := image.Pt(hudPadding, (hudHeight-size.Y)/2)
+	from := c.From.Sub(size.Div(2))
+	pos := from.Add(to.Sub(from).Mul(c.Age).Div(CoinFlightTicks))
+	op := &ebiten.DrawImageOptions{}
+	op.GeoM.Translate(float64(pos.X), float64(pos.Y))
+	clr := ColorDark
+	if pos.Y < hudHeight {
+		clr = ColorLight
+	}
+	drawSilhouette(screen, img, op.GeoM, clr)
+}
@@ -0,0 +1,39 @@
+// Copyright 2022 Siôn le Roux.  All rights reserved.
+// Use of this source code is subject to an MIT-style
+// licence which can be found in the LICENSE file.
+
+package main
+
+import "testing"
+
+func TestCoinDroppedOnDeath(t *testing.T) {
+	g := newTestGame()
+	c := NewSmallCreep(g)
+	c.Coords = WaypointCoords(g.Paths[0][1])
+	start := g.Money
+
+	c.Update(g)
+	if len(g.Coins) != 0 {
+		t.Fatalf("dropped %d coins from a live creep, want none", len(g.Coins))
+	}
+
+	c.Health = 0
+	c.Update(g)
+	if len(g.Coins) != 1 {
+		t.Fatalf("dropped %d coins when the creep died, want 1", len(g.Coins))
+	}
+	if got, want := g.Coins[0].From, g.ScreenCoords(c.Coords); got != want {
+		t.Errorf("coin dropped at %v, want where the creep died at %v", got, want)
+	}
+	paid := g.Money - start
+
+	for i := 0; i < CoinFlightTicks; i++ {
+		g.updateCoins()
+	}
+	if len(g.Coins) != 0 {
+		t.Errorf("%d coins still flying after %d ticks, want none", len(g.Coins), CoinFlightTicks)
+	}
+	if got := g.Money - start; got != paid || paid <= 0 {
+		t.Errorf("earned %d when the creep died and %d once the coin landed, want the same loot", paid, got)
+	}
+}
@@ -6,31 +6,71 @@ package main
 
 import (
 	"errors"
+	"fmt"
 	"image"
 	"log"
+	"math/rand/v2"
+	"time"
 
 	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
 )
 
 // Creep moves along a path from a spawn point towards the base it is attacking
 type Creep struct {
+	Kind         CreepKind
 	Coords       image.Point
+	PathIndex    int // Which of the map's paths it's following
 	NextWaypoint int
 	Health       int // Hit points
+	MaxHealth    int // Hit points it started with
 	Damage       int // How much damage it deals to the base
+	HitboxRadius int // How far from its middle it can be hit, in pixels
 	Loot         int // How much money you get when it dies
-	Frame        int
 	LastMoved    int
-	Direction    int  // Which way the creep is moving
-	Flip         bool // Whether to flip the animation frame
-	Sprite       *SpriteSheet
+	Direction    int          // Which way the creep is moving
+	Flip         bool         // Whether to flip the animation frame
+	FlipV        bool         // Whether to flip the animation frame upside down
+	Boss         bool         // Whether to show a health bar over it
+	SpawnDelay   int          // Ticks to wait after the creep before it to spawn
+	Leaked       bool         // Whether it reached the base
+	PoisonDamage int          // Damage the creep takes each tick while it's poisoned
+	PoisonTicks  int          // How many more ticks the creep is poisoned for
+	Regen        int          // Health the creep heals each tick when not being hurt
+	Shield       int          // Damage the creep can take from attacks before its health
+	MaxShield    int          // Shield it gets back after not being hurt for a while
+	Age          int          // Ticks since the creep was spawned
+	lastDamaged  int          // Age of the creep when it was last hurt
+	HitFlash     int          // How many more ticks to flash for after being hit
+	Dummy        bool         // Whether it's a target dummy for testing towers
+	Cloaked      bool         // Whether towers can only see it close up or with a detector
+	Enrages      bool         // Whether it moves faster the more hurt it is
+	Taken        []int        // Damage a target dummy took in each of the last few ticks
+	Sprite       *SpriteSheet // The sprite it's drawn with right now
+	SideSprite   *SpriteSheet // Sprite for moving sideways, if it has its own
+	UpSprite     *SpriteSheet // Sprite for moving up and down, if it has its own
+	Animation
 }
 
+// CreepKind says how a creep gets around, which decides which towers can hit
+// it, kinds can be combined to make a mask of the ones a tower can target
+type CreepKind int
+
+const (
+	creepKindGround CreepKind = 1 << iota
+	creepKindFlying
+
+	creepKindAll = creepKindGround | creepKindFlying
+)
+
 // NewTinyCreep returns a new creep with properties copied from creepTiny
 func NewTinyCreep(g *Game) *Creep {
 	return &Creep{
+		Kind:         creepKindGround,
 		NextWaypoint: 1,
 		Health:       200,
+		MaxHealth:    200,
+		HitboxRadius: 2,
 		Loot:         30,
 		Sprite:       g.Sprites[spriteTinyMonster],
 	}
@@ -39,8 +79,11 @@ func NewTinyCreep(g *Game) *Creep {
 // NewSmallCreep returns a new creep with properties copied from creepSmall
 func NewSmallCreep(g *Game) *Creep {
 	return &Creep{
+		Kind:         creepKindGround,
 		NextWaypoint: 1,
 		Health:       1000,
+		MaxHealth:    1000,
+		HitboxRadius: 3,
 		Loot:         50,
 		Sprite:       g.Sprites[spriteSmallMonster],
 	}
@@ -49,57 +92,211 @@ func NewSmallCreep(g *Game) *Creep {
 // NewBigCreep returns a new creep with properties copied from creepBig
 func NewBigCreep(g *Game) *Creep {
 	return &Creep{
+		Kind:         creepKindGround,
 		NextWaypoint: 1,
 		Health:       4500,
+		MaxHealth:    4500,
+		HitboxRadius: 5,
 		Loot:         200,
 		Sprite:       g.Sprites[spriteBigMonsterVertical],
+		SideSprite:   g.Sprites[spriteBigMonsterHorizont],
+		UpSprite:     g.Sprites[spriteBigMonsterVertical],
+	}
+}
+
+// NewBossCreep returns a new creep that ends a map, it looks like a big creep
+// but takes much more to kill and shows how much health it has left
+func NewBossCreep(g *Game) *Creep {
+	return &Creep{
+		Kind:         creepKindGround,
+		NextWaypoint: 1,
+		Health:       12000,
+		MaxHealth:    12000,
+		HitboxRadius: 5,
+		Loot:         800,
+		Boss:         true,
+		Sprite:       g.Sprites[spriteBigMonsterVertical],
+		SideSprite:   g.Sprites[spriteBigMonsterHorizont],
+		UpSprite:     g.Sprites[spriteBigMonsterVertical],
+	}
+}
+
+// NewFlyingCreep returns a new creep that flies over the path, so only towers
+// that can hit flying creeps can stop it
+func NewFlyingCreep(g *Game) *Creep {
+	return &Creep{
+		Kind:         creepKindFlying,
+		NextWaypoint: 1,
+		Health:       600,
+		MaxHealth:    600,
+		HitboxRadius: 2,
+		Loot:         60,
+		Sprite:       g.Sprites[spriteTinyMonster],
+	}
+}
+
+// NewHealerCreep returns a new armoured creep that heals itself when it isn't
+// being attacked, so you have to keep hitting it
+func NewHealerCreep(g *Game) *Creep {
+	return &Creep{
+		Kind:         creepKindGround,
+		NextWaypoint: 1,
+		Health:       2500,
+		MaxHealth:    2500,
+		HitboxRadius: 3,
+		Loot:         150,
+		Regen:        2,
+		Sprite:       g.Sprites[spriteSmallMonster],
+	}
+}
+
+// NewShieldedCreep returns a new creep with a shield that has to be broken
+// before it can be hurt, which comes back if you stop attacking it
+func NewShieldedCreep(g *Game) *Creep {
+	return &Creep{
+		Kind:         creepKindGround,
+		NextWaypoint: 1,
+		Health:       800,
+		MaxHealth:    800,
+		Shield:       600,
+		MaxShield:    600,
+		HitboxRadius: 3,
+		Loot:         120,
+		Sprite:       g.Sprites[spriteSmallMonster],
+	}
+}
+
+// NewStealthCreep returns a new cloaked creep that towers can't see until it
+// gets close to them, unless a detector tower reveals it
+func NewStealthCreep(g *Game) *Creep {
+	return &Creep{
+		Kind:         creepKindGround,
+		NextWaypoint: 1,
+		Health:       700,
+		MaxHealth:    700,
+		HitboxRadius: 2,
+		Loot:         90,
+		Cloaked:      true,
+		Sprite:       g.Sprites[spriteTinyMonster],
+	}
+}
+
+// NewEnragedCreep returns a new creep that gets faster as it gets hurt, so
+// it's hardest to stop just before it dies
+func NewEnragedCreep(g *Game) *Creep {
+	return &Creep{
+		Kind:         creepKindGround,
+		NextWaypoint: 1,
+		Health:       1500,
+		MaxHealth:    1500,
+		HitboxRadius: 3,
+		Loot:         100,
+		Enrages:      true,
+		Sprite:       g.Sprites[spriteSmallMonster],
+	}
+}
+
+// How many ticks creeps wait between each step they take
+const MoveInterval = 10
+
+// How many ticks the creep waits between steps, enraged creeps wait less
+// once they're down to half their health and even less at a quarter
+func (c *Creep) moveInterval() int {
+	if !c.Enrages || c.MaxHealth <= 0 {
+		return MoveInterval
+	}
+	switch {
+	case c.Health*4 <= c.MaxHealth:
+		return MoveInterval / 2
+	case c.Health*2 <= c.MaxHealth:
+		return MoveInterval * 3 / 4
+	default:
+		return MoveInterval
 	}
 }
 
-// NewWaves makes new waves of creeps
+// How much more loot creeps give in each wave after the first, in percent
+const LootScalePercent = 25
+
+// Scale a creep's base loot up for later waves, so the economy can keep up
+func scaleLoot(loot, wave int) int {
+	return loot + loot*wave*LootScalePercent/100
+}
+
+// Constructors for each kind of creep by the name used in wave data
+var creepKinds = map[string]func(g *Game) *Creep{
+	"tiny":     NewTinyCreep,
+	"small":    NewSmallCreep,
+	"big":      NewBigCreep,
+	"boss":     NewBossCreep,
+	"flyer":    NewFlyingCreep,
+	"healer":   NewHealerCreep,
+	"shielded": NewShieldedCreep,
+	"stealth":  NewStealthCreep,
+	"enraged":  NewEnragedCreep,
+}
+
+// WaveSegment is part of a wave where a number of creeps of the same kind are
+// sent one after the other with a fixed interval between them
+type WaveSegment struct {
+	Creep    string `json:"creep"`    // The kind of creep to send
+	Count    int    `json:"count"`    // How many creeps to send
+	Interval int    `json:"interval"` // Ticks to wait before sending each one
+}
+
+// NewWaves makes new waves of creeps from each map's wave data
 func NewWaves(g *Game) []Creeps {
 	return []Creeps{
-		{
-			NewSmallCreep(g),
-			NewSmallCreep(g),
-			NewSmallCreep(g),
-			NewSmallCreep(g),
-			NewSmallCreep(g),
-			NewSmallCreep(g),
-			NewSmallCreep(g),
-			NewSmallCreep(g),
-			NewSmallCreep(g),
-			NewSmallCreep(g),
-			NewSmallCreep(g),
-			NewSmallCreep(g),
-			NewSmallCreep(g),
-			NewSmallCreep(g),
-			NewSmallCreep(g),
-			NewBigCreep(g),
-		},
-		{
-			NewTinyCreep(g),
-			NewTinyCreep(g),
-			NewSmallCreep(g),
-			NewSmallCreep(g),
-			NewSmallCreep(g),
-			NewTinyCreep(g),
-			NewTinyCreep(g),
-			NewSmallCreep(g),
-			NewBigCreep(g),
-			NewSmallCreep(g),
-			NewSmallCreep(g),
-			NewTinyCreep(g),
-			NewTinyCreep(g),
-			NewSmallCreep(g),
-			NewBigCreep(g),
-			NewSmallCreep(g),
-			NewSmallCreep(g),
-			NewBigCreep(g),
-		},
+		NewWave(g, g.MapData1.Wave, 0),
+		NewWave(g, g.MapData2.Wave, 1),
 	}
 }
 
+// NewWave makes the creeps for one wave in the order they'll be sent, each
+// knowing how long to wait after the one before it
+func NewWave(g *Game, segments []WaveSegment, index int) Creeps {
+	var wave Creeps
+	for _, s := range segments {
+		newCreep, ok := creepKinds[s.Creep]
+		if !ok {
+			log.Printf("Skipping unknown creep %q in wave %d\n", s.Creep, index)
+			continue
+		}
+		interval := s.Interval
+		if interval <= 0 {
+			interval = SpawnInterval
+		}
+		for i := 0; i < max(1, s.Count); i++ {
+			c := newCreep(g)
+			c.Loot = scaleLoot(c.Loot, index)
+			c.SpawnDelay = interval
+			wave = append(wave, c)
+		}
+	}
+	if g.Seed != 0 {
+		shuffleWave(wave, g.Seed, index)
+	}
+	return wave
+}
+
+// Mix up the order creeps in a wave are sent in, the same way every time for
+// the same seed so that a saved game gets the same wave back
+func shuffleWave(wave Creeps, seed uint64, index int) {
+	r := rand.New(rand.NewPCG(seed, uint64(index)))
+	r.Shuffle(len(wave), func(i, j int) {
+		wave[i], wave[j] = wave[j], wave[i]
+	})
+}
+
+// Pick a new seed for shuffling the waves if shuffling is turned on, or zero
+// to send creeps in the order they were designed in
+func (g *Game) newSeed() uint64 {
+	if !g.Settings.Shuffle {
+		return 0
+	}
+	return uint64(time.Now().UnixNano()) | 1
+}
+
 const (
 	directionRight int = iota
 	directionLeft
@@ -109,59 +306,147 @@ const (
 
 // Update handles game logic for a Creep
 func (c *Creep) Update(g *Game) error {
+	if c.PoisonTicks > 0 {
+		g.forgetPlacements()
+	}
+	c.tickPoison()
+	if c.Dummy {
+		c.measureDamage()
+		return nil
+	}
 	if c.Health <= 0 {
-		g.Money += c.Loot
+		g.Kills++
+		g.earn(c.Loot + g.recordKill())
+		g.dropCoin(c)
 		return errors.New("Creep died")
 	}
+	if c.Leaked {
+		return errors.New("Creep reached the base")
+	}
+	c.Age++
+	c.regenerate()
+	c.restoreShield()
+	if c.HitFlash > 0 {
+		c.HitFlash--
+	}
 
-	c.LastMoved = (c.LastMoved + 1) % 10
-	if c.LastMoved != 0 {
+	c.animate()
+
+	c.LastMoved++
+	if c.LastMoved < c.moveInterval() {
 		return nil
 	}
+	c.LastMoved = 0
 
 	c.navigateWaypoints(g)
-	c.animate()
 
 	return nil
 }
 
+// Kill streaks give bonus money for each kill made soon after the last one
+const (
+	StreakWindow = 60 // How many ticks you have to make the next kill
+	StreakBonus  = 5  // Extra money for each kill in the streak after the first
+)
+
+// Count a kill towards the current kill streak, or start a new streak if the
+// last kill was too long ago, and return the bonus money for it
+func (g *Game) recordKill() int {
+	if g.KillStreak > 0 && g.Tick-g.LastKillTick <= StreakWindow {
+		g.KillStreak++
+	} else {
+		g.KillStreak = 1
+	}
+	g.LastKillTick = g.Tick
+	if g.KillStreak > 1 {
+		g.ShowNotice(fmt.Sprintf("x%d", g.KillStreak))
+	}
+	return StreakBonus * (g.KillStreak - 1)
+}
+
+// Names of the frame tags in creep sprites
+const (
+	creepTagHorizontal = "horizontal_moving"
+	creepTagVertical   = "vertical_moving"
+)
+
 func (c *Creep) animate() {
-	const (
-		HORIZONTAL = 0
-		VERTICAL   = 2
-	)
-	var frameTag int
+	var tagName string
+	c.Flip = c.Direction == directionLeft
+	c.FlipV = c.Direction == directionUp // Vertical frames face down
 	switch c.Direction {
-	case directionRight:
-		c.Flip = false
-		frameTag = HORIZONTAL
-	case directionLeft:
-		c.Flip = true
-		frameTag = HORIZONTAL
+	case directionRight, directionLeft:
+		tagName = creepTagHorizontal
 	default:
-		c.Flip = false
-		frameTag = VERTICAL
+		tagName = creepTagVertical
 	}
-	from := c.Sprite.Meta.FrameTags[frameTag].From
-	to := c.Sprite.Meta.FrameTags[frameTag].To
-	if c.Frame < from || c.Frame >= to {
-		c.Frame = from
-		return
+	if s := axisSprite(c.Direction, c.Sprite, c.SideSprite, c.UpSprite); s != c.Sprite {
+		c.Sprite = s
+		c.Animation = Animation{}
 	}
-	if c.Frame < to {
-		c.Frame++
+	c.Play(c.Sprite.TagOrAll(tagName), true) // Sprites without tags loop every frame
+	c.Step(c.Sprite.Sprite)
+}
+
+// Choose the sprite for a creep moving in a direction, from the ones it has
+// for moving sideways and up and down, keeping the current one if it doesn't
+// have one for that direction
+func axisSprite(direction int, current, side, up *SpriteSheet) *SpriteSheet {
+	next := up
+	if direction == directionLeft || direction == directionRight {
+		next = side
+	}
+	if next == nil {
+		return current
 	}
+	return next
 }
 
-func (c *Creep) navigateWaypoints(g *Game) {
+// WaypointCoords is the pixel position a creep heads for to reach a waypoint,
+// which is the middle of its tile
+func WaypointCoords(w *Waypoint) image.Point {
 	tileSize := 7
 	hudOffset := 5
 	tileCenter := 4
-	targetSquare := g.MapData[c.NextWaypoint]
-	targertCoords := image.Pt(
-		targetSquare.X*tileSize+tileCenter,
-		targetSquare.Y*tileSize+tileCenter+hudOffset,
+	return image.Pt(
+		w.X*tileSize+tileCenter,
+		w.Y*tileSize+tileCenter+hudOffset,
 	)
+}
+
+// Says whether creep a is further along the path towards the base than
+// creep b, by which waypoint it's heading for and how close it is to it
+func furtherAlong(g *Game, a, b *Creep) bool {
+	aLeft := len(a.Path(g)) - a.NextWaypoint
+	bLeft := len(b.Path(g)) - b.NextWaypoint
+	if aLeft != bLeft {
+		return aLeft < bLeft
+	}
+	if a.PathIndex != b.PathIndex {
+		return false // No way of telling, they're heading for different places
+	}
+	target := WaypointCoords(a.Path(g)[a.NextWaypoint])
+	return distanceSquared(a.Coords, target) < distanceSquared(b.Coords, target)
+}
+
+// Square of the distance between two points, which is enough for comparing
+func distanceSquared(a, b image.Point) int {
+	d := a.Sub(b)
+	return d.X*d.X + d.Y*d.Y
+}
+
+// Path is the waypoints the creep is following to the base
+func (c *Creep) Path(g *Game) Ways {
+	return g.Paths[c.PathIndex]
+}
+
+func (c *Creep) navigateWaypoints(g *Game) {
+	path := c.Path(g)
+	if c.NextWaypoint >= len(path) {
+		g.leak(c) // Nowhere left to go
+		return
+	}
+	targertCoords := WaypointCoords(path[c.NextWaypoint])
 	if targertCoords.X > c.Coords.X {
 		c.Coords.X++
 		c.Direction = directionRight
@@ -172,26 +457,148 @@ func (c *Creep) navigateWaypoints(g *Game) {
 	}
 	if targertCoords.Y > c.Coords.Y {
 		c.Coords.Y++
-		c.Direction = directionUp
+		c.Direction = directionDown
 	}
 	if targertCoords.Y < c.Coords.Y {
 		c.Coords.Y--
-		c.Direction = directionDown
+		c.Direction = directionUp
 	}
 	if targertCoords.X == c.Coords.X && targertCoords.Y == c.Coords.Y {
 		next := c.NextWaypoint + 1
-		if next < len(g.MapData) {
+		if next < len(path) {
 			c.NextWaypoint++
 		} else {
-			log.Println("You failed")
-			g.State = gameStateLose
+			g.leak(c)
 		}
 	}
 }
 
-// Attack hurts a creep's health by a specified amount
+// How many ticks the base flashes for when a creep reaches it
+const BaseFlashTicks = 30
+
+// A creep reached the base, which breaks one of your hearts, and you lose once
+// they're all gone
+func (g *Game) leak(c *Creep) {
+	path := c.Path(g)
+	base := WaypointCoords(path[len(path)-1])
+	g.Leaks++
+	g.Effects = append(g.Effects, NewEffect(g.Sprites[spriteHeartGone], base))
+	g.BaseFlash = BaseFlashTicks
+	if g.Sandbox {
+		c.NextWaypoint = 1
+		c.Coords = WaypointCoords(path[0])
+		return
+	}
+	c.Leaked = true
+	g.BaseWear += leakDamage(c, g.Settings.LastStand)
+	g.Lives = max(0, g.Lives-g.BaseWear/100)
+	g.BaseWear %= 100
+	if g.Lives <= 0 && g.State != gameStateOverrun {
+		log.Println("You failed")
+		g.State = gameStateLose
+	}
+}
+
+// KnockBack pushes the creep back along its path by some pixels, towards the
+// waypoints it has already passed, but never further back than its spawn
+func (c *Creep) KnockBack(g *Game, pixels int) {
+	path := c.Path(g)
+	for i := 0; i < pixels && c.NextWaypoint > 0; i++ {
+		prev := WaypointCoords(path[c.NextWaypoint-1])
+		if c.Coords == prev {
+			if c.NextWaypoint == 1 {
+				return
+			}
+			c.NextWaypoint--
+			prev = WaypointCoords(path[c.NextWaypoint-1])
+		}
+		c.Coords = c.Coords.Add(image.Pt(sign(prev.X-c.Coords.X), sign(prev.Y-c.Coords.Y)))
+	}
+}
+
+// Sign of an integer, -1, 0 or 1
+func sign(x int) int {
+	switch {
+	case x < 0:
+		return -1
+	case x > 0:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// How much of a life a creep takes when it reaches the base, in hundredths,
+// which is all of its damage normally but in last stand mode only as much of
+// it as the creep has health left, so hurting it still helps
+func leakDamage(c *Creep, lastStand bool) int {
+	damage := max(1, c.Damage) * 100
+	if !lastStand || c.MaxHealth <= 0 {
+		return damage
+	}
+	return max(1, (damage*max(0, c.Health)+c.MaxHealth-1)/c.MaxHealth)
+}
+
+// The most damage per tick poison can stack up to on one creep
+const MaxPoisonDamage = 5
+
+// Poison makes the creep take damage every tick for a while, stacking with
+// any poison it already has and lasting for the longer of the two
+func (c *Creep) Poison(damage, ticks int) {
+	c.PoisonDamage = min(MaxPoisonDamage, c.PoisonDamage+damage)
+	c.PoisonTicks = max(c.PoisonTicks, ticks)
+}
+
+// Hurt the creep by the poison it has for one tick
+func (c *Creep) tickPoison() {
+	if c.PoisonTicks <= 0 {
+		return
+	}
+	c.hurt(c.PoisonDamage)
+	c.PoisonTicks--
+	if c.PoisonTicks == 0 {
+		c.PoisonDamage = 0
+	}
+}
+
+// How many ticks after being hurt a creep starts to heal again
+const RegenDelay = 60
+
+// Heal the creep a bit if it hasn't been hurt for a while
+func (c *Creep) regenerate() {
+	if c.Regen <= 0 || c.Age-c.lastDamaged <= RegenDelay {
+		return
+	}
+	c.Health = min(c.MaxHealth, c.Health+c.Regen)
+}
+
+// How many ticks after being hurt a creep's shield comes back
+const ShieldDelay = 180
+
+// Bring the creep's shield back to full if it hasn't been hurt for a while
+func (c *Creep) restoreShield() {
+	if c.Shield >= c.MaxShield || c.Age-c.lastDamaged <= ShieldDelay {
+		return
+	}
+	c.Shield = c.MaxShield
+}
+
+// How many ticks a creep flashes for after being hit
+const HitFlashTicks = 3
+
+// Attack hurts a creep by a specified amount, taking it off its shield first
+// if it has one and then off its health
 func (c *Creep) Attack(amount int) bool {
+	c.HitFlash = HitFlashTicks
+	absorbed := min(c.Shield, amount)
+	c.Shield -= absorbed
+	return c.hurt(amount - absorbed)
+}
+
+// Take some health off the creep without flashing, saying whether it died
+func (c *Creep) hurt(amount int) bool {
 	c.Health = c.Health - amount
+	c.lastDamaged = c.Age
 	if c.Health <= 0 {
 		return true
 	}
@@ -200,21 +607,93 @@ func (c *Creep) Attack(amount int) bool {
 
 // Draw draws the Creep to the screen
 func (c *Creep) Draw(g *Game, screen *ebiten.Image) {
+	c.drawSprite(g, screen)
+	pos := g.ScreenCoords(c.Coords)
+
+	// Shielded creeps have a box around them until the shield is broken
+	if c.Shield > 0 {
+		drawOutline(screen, image.Rect(pos.X-4, pos.Y-4, pos.X+5, pos.Y+5), ColorDark)
+	}
+
+	if c.Boss || g.ShowHealthBars {
+		c.drawHealthBar(g, screen)
+	}
+
+	if c.Dummy {
+		c.drawDPS(g, screen)
+	}
+}
+
+// Draw the current frame of the creep's animation facing the way it's going,
+// unless its sprite has no frames to draw
+func (c *Creep) drawSprite(g *Game, screen *ebiten.Image) {
 	s := c.Sprite
-	frame := s.Sprite[c.Frame]
-	op := &ebiten.DrawImageOptions{}
-	if c.Flip { // Please don't ask
-		op.GeoM.Translate(float64(-1*frame.Position.W/2), 1)
-		op.GeoM.Scale(-1, 1)
-		op.GeoM.Translate(float64(frame.Position.W/2), 1)
+	i, ok := clampFrame(s, c.CurrentFrame())
+	if !ok {
+		return
 	}
-	op.GeoM.Translate(float64(c.Coords.X-3), float64(c.Coords.Y-3))
-	screen.DrawImage(s.Image.SubImage(image.Rect(
+	frame := s.Sprite[i]
+	op := &ebiten.DrawImageOptions{}
+	op.GeoM = c.facingGeoM(frame.Position.W, frame.Position.H)
+	pos := g.ScreenCoords(c.Coords)
+	op.GeoM.Translate(float64(pos.X-3), float64(pos.Y-3))
+	img := s.Image.SubImage(image.Rect(
 		frame.Position.X,
 		frame.Position.Y,
 		frame.Position.X+frame.Position.W,
 		frame.Position.Y+frame.Position.H,
-	)).(*ebiten.Image), op)
+	)).(*ebiten.Image)
+	// Cloaked creeps flicker until a detector reveals them
+	hidden := c.Cloaked && !g.Revealed(c) && (c.Age/4)%2 == 0
+	if c.HitFlash > 0 {
+		drawSilhouette(screen, img, op.GeoM, ColorLight)
+	} else if !hidden {
+		screen.DrawImage(img, op)
+	}
+}
+
+// The transform that turns a frame of the creep's sprite of the given size to
+// face the way it's going, before it's moved to where the creep is
+func (c *Creep) facingGeoM(w, h int) ebiten.GeoM {
+	var geoM ebiten.GeoM
+	if c.FlipV {
+		geoM.Scale(1, -1)
+		geoM.Translate(0, float64(h))
+	}
+	if c.Flip { // Please don't ask
+		geoM.Translate(float64(-1*w/2), 1)
+		geoM.Scale(-1, 1)
+		geoM.Translate(float64(w/2), 1)
+	}
+	return geoM
+}
+
+// Size of the bar showing how much health a creep has left
+const (
+	healthBarWidth  = 7
+	healthBarHeight = 2
+)
+
+// Work out how many pixels of a health bar should be filled, rounding up so
+// that the bar only empties completely when the creep is dead
+func healthBarFill(health, maxHealth, width int) int {
+	if maxHealth <= 0 || health <= 0 {
+		return 0
+	}
+	if health >= maxHealth {
+		return width
+	}
+	return (health*width + maxHealth - 1) / maxHealth
+}
+
+// Draw a bar over the creep showing how much health it has left
+func (c *Creep) drawHealthBar(g *Game, screen *ebiten.Image) {
+	pos := g.ScreenCoords(c.Coords)
+	x := float64(pos.X - healthBarWidth/2)
+	y := float64(pos.Y - 4 - healthBarHeight)
+	fill := healthBarFill(c.Health, c.MaxHealth, healthBarWidth)
+	ebitenutil.DrawRect(screen, x, y, healthBarWidth, healthBarHeight, ColorLight)
+	ebitenutil.DrawRect(screen, x, y, float64(fill), healthBarHeight, ColorDark)
 }
 
 // Creeps is a slice of Creep entities
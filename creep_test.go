@@ -0,0 +1,416 @@
+// Copyright 2022 Siôn le Roux.  All rights reserved.
+// Use of this source code is subject to an MIT-style
+// licence which can be found in the LICENSE file.
+
+package main
+
+import (
+	"image"
+	"maps"
+	"slices"
+	"testing"
+)
+
+func TestHealerRegen(t *testing.T) {
+	g := newTestGame()
+	c := NewHealerCreep(g)
+	c.Attack(100)
+	hurt := c.Health
+
+	for i := 0; i < RegenDelay; i++ {
+		c.Age++
+		c.regenerate()
+	}
+	if c.Health != hurt {
+		t.Fatalf("healed to %d within %d ticks of being hurt, want %d", c.Health, RegenDelay, hurt)
+	}
+	c.Age++
+	c.regenerate()
+	if want := hurt + c.Regen; c.Health != want {
+		t.Fatalf("healed to %d after the delay, want %d", c.Health, want)
+	}
+
+	c.Health = c.MaxHealth - 1
+	c.Age++
+	c.regenerate()
+	if c.Health != c.MaxHealth {
+		t.Errorf("healed to %d, want no more than the maximum %d", c.Health, c.MaxHealth)
+	}
+}
+
+func TestShieldAbsorbsDamage(t *testing.T) {
+	g := newTestGame()
+	c := NewShieldedCreep(g)
+
+	c.Attack(c.MaxShield - 100)
+	if c.Shield != 100 || c.Health != c.MaxHealth {
+		t.Fatalf("shield %d and health %d after a small hit, want 100 and %d", c.Shield, c.Health, c.MaxHealth)
+	}
+	c.Attack(150)
+	if c.Shield != 0 || c.Health != c.MaxHealth-50 {
+		t.Fatalf("shield %d and health %d after breaking the shield, want 0 and %d",
+			c.Shield, c.Health, c.MaxHealth-50)
+	}
+}
+
+func TestShieldRestores(t *testing.T) {
+	g := newTestGame()
+	c := NewShieldedCreep(g)
+	c.Attack(c.MaxShield)
+
+	for i := 0; i < ShieldDelay; i++ {
+		c.Age++
+		c.restoreShield()
+	}
+	if c.Shield != 0 {
+		t.Fatalf("shield came back to %d within %d ticks of being hit, want 0", c.Shield, ShieldDelay)
+	}
+	c.Age++
+	c.restoreShield()
+	if c.Shield != c.MaxShield {
+		t.Errorf("shield came back to %d after the delay, want %d", c.Shield, c.MaxShield)
+	}
+}
+
+// How many pixels along its path a creep has come from its spawn point
+func pathProgress(path Ways, c *Creep) int {
+	progress := 0
+	for i := 1; i < c.NextWaypoint; i++ {
+		d := WaypointCoords(path[i]).Sub(WaypointCoords(path[i-1]))
+		progress += abs(d.X) + abs(d.Y)
+	}
+	d := c.Coords.Sub(WaypointCoords(path[c.NextWaypoint-1]))
+	return progress + abs(d.X) + abs(d.Y)
+}
+
+func TestKnockBack(t *testing.T) {
+	spawn := WaypointCoords(testMapData.Ways[0])
+	corner := WaypointCoords(testMapData.Ways[1])
+	tests := []struct {
+		name   string
+		coords image.Point
+		next   int
+		pixels int
+		want   int // Pixels of progress lost
+	}{
+		{"along the first stretch", spawn.Add(image.Pt(10, 0)), 1, 4, 4},
+		{"back past the spawn", spawn.Add(image.Pt(3, 0)), 1, 10, 3},
+		{"from the spawn", spawn, 1, 5, 0},
+		{"from a corner", corner, 2, 5, 5},
+		{"back round a corner", corner.Add(image.Pt(0, 2)), 2, 5, 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := newTestGame()
+			c := NewSmallCreep(g)
+			c.Coords = tt.coords
+			c.NextWaypoint = tt.next
+			path := c.Path(g)
+			before := pathProgress(path, c)
+
+			c.KnockBack(g, tt.pixels)
+			if c.NextWaypoint < 1 {
+				t.Fatalf("knocked back to waypoint %d, past the spawn", c.NextWaypoint)
+			}
+			after := pathProgress(path, c)
+			if lost := before - after; lost != tt.want {
+				t.Errorf("knocked back %d pixels along the path to %v, want %d", lost, c.Coords, tt.want)
+			}
+			if c.NextWaypoint == 1 && c.Coords.X < spawn.X { // The first stretch heads right
+				t.Errorf("knocked back to %v, past the spawn at %v", c.Coords, spawn)
+			}
+		})
+	}
+}
+
+func TestHealthBarFill(t *testing.T) {
+	g := newTestGame()
+	c := NewBossCreep(g)
+	step := c.MaxHealth / 10
+
+	last := healthBarFill(c.Health, c.MaxHealth, healthBarWidth)
+	if last != healthBarWidth {
+		t.Fatalf("full health fills %d pixels, want %d", last, healthBarWidth)
+	}
+	for !c.Attack(step) {
+		fill := healthBarFill(c.Health, c.MaxHealth, healthBarWidth)
+		if fill > last || fill <= 0 {
+			t.Errorf("%d of %d health fills %d pixels after %d, want it to shrink without emptying",
+				c.Health, c.MaxHealth, fill, last)
+		}
+		last = fill
+	}
+	if fill := healthBarFill(c.Health, c.MaxHealth, healthBarWidth); fill != 0 {
+		t.Errorf("dead creep fills %d pixels, want 0", fill)
+	}
+
+	tests := []struct{ health, want int }{
+		{12000, 7}, {11999, 7}, {6000, 4}, {1714, 1}, {1715, 2}, {1, 1}, {0, 0}, {-50, 0},
+	}
+	for _, tt := range tests {
+		if got := healthBarFill(tt.health, 12000, healthBarWidth); got != tt.want {
+			t.Errorf("healthBarFill(%d, 12000, %d) = %d, want %d", tt.health, healthBarWidth, got, tt.want)
+		}
+	}
+}
+
+func TestCreepMaxHealth(t *testing.T) {
+	g := newTestGame()
+	for name, newCreep := range creepKinds {
+		c := newCreep(g)
+		if c.MaxHealth <= 0 || c.Health != c.MaxHealth {
+			t.Errorf("%s creep starts with %d of %d health, want full health", name, c.Health, c.MaxHealth)
+		}
+	}
+}
+
+func TestLootScalesWithWave(t *testing.T) {
+	g := newTestGame()
+	segments := []WaveSegment{{Creep: "small", Count: 1}}
+	early := NewWave(g, segments, 0)[0]
+	late := NewWave(g, segments, 1)[0]
+	if late.Loot <= early.Loot {
+		t.Errorf("small creep in a later wave gives %d loot, want more than the %d it gives early on",
+			late.Loot, early.Loot)
+	}
+	if base := NewSmallCreep(g).Loot; early.Loot != base {
+		t.Errorf("small creep in the first wave gives %d loot, want its base %d", early.Loot, base)
+	}
+}
+
+func TestKillStreak(t *testing.T) {
+	g := newTestGame()
+	tests := []struct {
+		name      string
+		tick      int
+		streak    int
+		wantBonus int
+	}{
+		{"first kill", 100, 1, 0},
+		{"quick second kill", 100 + StreakWindow, 2, StreakBonus},
+		{"quick third kill", 100 + 2*StreakWindow, 3, 2 * StreakBonus},
+		{"too slow", 101 + 3*StreakWindow, 1, 0},
+		{"quick again", 101 + 3*StreakWindow + 1, 2, StreakBonus},
+	}
+	for _, tt := range tests {
+		g.Tick = tt.tick
+		bonus := g.recordKill()
+		if g.KillStreak != tt.streak || bonus != tt.wantBonus {
+			t.Errorf("%s: streak %d with bonus %d, want %d with bonus %d",
+				tt.name, g.KillStreak, bonus, tt.streak, tt.wantBonus)
+		}
+	}
+}
+
+func TestPoison(t *testing.T) {
+	g := newTestGame()
+	c := NewBigCreep(g)
+	c.Coords = WaypointCoords(g.Paths[0][0])
+	c.Poison(2, 10)
+
+	for i := 0; i < 15; i++ {
+		c.Update(g)
+	}
+	if lost := c.MaxHealth - c.Health; lost != 2*10 {
+		t.Errorf("poison took %d health, want %d", lost, 2*10)
+	}
+	if c.PoisonTicks != 0 || c.PoisonDamage != 0 {
+		t.Errorf("poison left at %d damage for %d ticks, want it gone", c.PoisonDamage, c.PoisonTicks)
+	}
+
+	c.Health = 5
+	c.Poison(2, 10)
+	money := g.Money
+	var err error
+	for i := 0; i < 10 && err == nil; i++ {
+		err = c.Update(g)
+	}
+	if err == nil || c.Health > 0 {
+		t.Fatalf("creep with %d health survived the poison", c.Health)
+	}
+	if earned := g.Money - money; earned != c.Loot {
+		t.Errorf("poison kill earned %d, want the creep's loot %d", earned, c.Loot)
+	}
+}
+
+func TestKillAndLeakCounters(t *testing.T) {
+	g := newTestGame()
+
+	dead := NewSmallCreep(g)
+	dead.Health = 0
+	dead.Update(g)
+	if g.Kills != 1 || g.Leaks != 0 {
+		t.Errorf("after a kill counted %d kills and %d leaks, want 1 and 0", g.Kills, g.Leaks)
+	}
+
+	path := g.Paths[0]
+	leaker := NewSmallCreep(g)
+	leaker.Coords = WaypointCoords(path[len(path)-1])
+	leaker.NextWaypoint = len(path)
+	for i := 0; i < 100 && !leaker.Leaked; i++ {
+		leaker.Update(g)
+	}
+	if g.Kills != 1 || g.Leaks != 1 {
+		t.Errorf("after a leak counted %d kills and %d leaks, want 1 and 1", g.Kills, g.Leaks)
+	}
+}
+
+func TestHitFlash(t *testing.T) {
+	g := newTestGame()
+	c := NewBigCreep(g)
+	c.Coords = WaypointCoords(g.Paths[0][0])
+	if c.HitFlash != 0 {
+		t.Fatalf("new creep is flashing for %d ticks", c.HitFlash)
+	}
+
+	c.Attack(10)
+	if c.HitFlash != HitFlashTicks {
+		t.Fatalf("hit creep flashes for %d ticks, want %d", c.HitFlash, HitFlashTicks)
+	}
+	for i := HitFlashTicks - 1; i >= 0; i-- {
+		c.Update(g)
+		if c.HitFlash != i {
+			t.Errorf("creep flashing for %d more ticks, want %d", c.HitFlash, i)
+		}
+	}
+	c.Update(g)
+	if c.HitFlash != 0 {
+		t.Errorf("creep still flashing for %d ticks after the flash ran out", c.HitFlash)
+	}
+}
+
+func TestBigCreepSpriteFollowsAxis(t *testing.T) {
+	g := newTestGame()
+	horizontal := g.Sprites[spriteBigMonsterHorizont]
+	vertical := g.Sprites[spriteBigMonsterVertical]
+	tests := []struct {
+		direction int
+		want      *SpriteSheet
+	}{
+		{directionRight, horizontal},
+		{directionDown, vertical},
+		{directionLeft, horizontal},
+		{directionUp, vertical},
+	}
+	c := NewBigCreep(g)
+	for _, tt := range tests {
+		c.Direction = tt.direction
+		c.animate()
+		if c.Sprite != tt.want {
+			t.Errorf("big creep moving in direction %d uses the wrong sprite", tt.direction)
+		}
+	}
+}
+
+func TestLeakDamage(t *testing.T) {
+	tests := []struct {
+		name      string
+		damage    int
+		health    int
+		lastStand bool
+		want      int
+	}{
+		{"flat at full health", 2, 1000, false, 200},
+		{"flat when hurt", 2, 250, false, 200},
+		{"last stand at full health", 2, 1000, true, 200},
+		{"last stand at half health", 2, 500, true, 100},
+		{"last stand at a quarter health", 1, 250, true, 25},
+		{"last stand rounds up", 1, 1, true, 1},
+		{"no damage still takes something", 0, 1000, false, 100},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Creep{Damage: tt.damage, Health: tt.health, MaxHealth: 1000}
+			if got := leakDamage(c, tt.lastStand); got != tt.want {
+				t.Errorf("leakDamage(%d/%d health, %v) = %d, want %d",
+					tt.health, c.MaxHealth, tt.lastStand, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestShuffleWave(t *testing.T) {
+	g := newTestGame()
+	segments := []WaveSegment{
+		{Creep: "tiny", Count: 5},
+		{Creep: "small", Count: 3},
+		{Creep: "big", Count: 2},
+	}
+	healths := func(wave Creeps) []int {
+		var hs []int
+		for _, c := range wave {
+			hs = append(hs, c.MaxHealth)
+		}
+		return hs
+	}
+	counts := func(wave Creeps) map[int]int {
+		n := map[int]int{}
+		for _, c := range wave {
+			n[c.MaxHealth]++
+		}
+		return n
+	}
+
+	g.Seed = 0
+	designed := NewWave(g, segments, 0)
+	g.Seed = 12345
+	first := NewWave(g, segments, 0)
+	second := NewWave(g, segments, 0)
+
+	if got, want := healths(second), healths(first); !slices.Equal(got, want) {
+		t.Errorf("shuffled twice with the same seed got %v then %v", want, got)
+	}
+	if got := healths(first); slices.Equal(got, healths(designed)) {
+		t.Errorf("shuffled wave %v is still in the designed order", got)
+	}
+	if got, want := counts(first), counts(designed); !maps.Equal(got, want) {
+		t.Errorf("shuffled wave has counts %v, want %v", got, want)
+	}
+}
+
+func TestEnragedMoveInterval(t *testing.T) {
+	g := newTestGame()
+	tests := []struct {
+		name   string
+		creep  *Creep
+		health int // In percent of its max health
+		want   int
+	}{
+		{"enraged at full health", NewEnragedCreep(g), 100, MoveInterval},
+		{"enraged just over half", NewEnragedCreep(g), 51, MoveInterval},
+		{"enraged at half", NewEnragedCreep(g), 50, MoveInterval * 3 / 4},
+		{"enraged just over a quarter", NewEnragedCreep(g), 26, MoveInterval * 3 / 4},
+		{"enraged at a quarter", NewEnragedCreep(g), 25, MoveInterval / 2},
+		{"calm at a quarter", NewSmallCreep(g), 25, MoveInterval},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.creep.Health = tt.creep.MaxHealth * tt.health / 100
+			if got := tt.creep.moveInterval(); got != tt.want {
+				t.Errorf("moveInterval() at %d%% health = %d, want %d", tt.health, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerticalFlip(t *testing.T) {
+	g := newTestGame()
+	c := NewSmallCreep(g)
+	facing := func(direction int) (bool, float64, float64) {
+		c.Direction = direction
+		c.animate()
+		geoM := c.facingGeoM(7, 7)
+		x, y := geoM.Apply(0, 0)
+		return c.FlipV, x, y
+	}
+
+	upFlip, upX, upY := facing(directionUp)
+	downFlip, downX, downY := facing(directionDown)
+	if !upFlip || downFlip {
+		t.Errorf("FlipV moving up = %v and down = %v, want only up flipped", upFlip, downFlip)
+	}
+	if upX == downX && upY == downY {
+		t.Errorf("top-left of the sprite drawn at (%v, %v) both moving up and down, want them flipped apart",
+			upX, upY)
+	}
+}
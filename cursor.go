@@ -15,21 +15,29 @@ import (
 type Cursor struct {
 	Coords     image.Point
 	Width      int
-	Image      *ebiten.Image
-	Cooldown   int // Wait to show off construction animation
-	BlinkCount int // Wait to blink the cursor
+	Image      *ebiten.Image // Image shown right now, depending on the mode
+	CrossImage *ebiten.Image // Crosshair shown normally
+	SellImage  *ebiten.Image // X shown in sell mode
+	SellMode   bool          // Whether pressing action sells instead of builds
+	Cooldown   int           // Wait to show off construction animation
+	CooldownOf int           // How long the wait was when it started
+	BuildAt    image.Point   // Where the tower being shown off was built
+	BlinkCount int           // Wait to blink the cursor
 	BlinkOn    bool
+	Rejected   int // Ticks left to flash that the last action was rejected
 }
 
+// How long the cursor flashes for when a build is rejected
+const RejectFlashTicks = 20
+
 // Update implements Entity
 func (c *Cursor) Update(g *Game) error {
-	oldPos := c.Coords
-	tileSize := 7
-	hudOffset := 5
-
 	if c.Cooldown > 0 {
 		c.Cooldown--
 	}
+	if c.Rejected > 0 {
+		c.Rejected--
+	}
 
 	blinkAfter := 40
 	c.BlinkCount = (c.BlinkCount + 1) % blinkAfter
@@ -37,29 +45,59 @@ func (c *Cursor) Update(g *Game) error {
 		c.BlinkOn = !c.BlinkOn
 	}
 
+	return nil
+}
+
+// HandleInput moves the cursor a tile at a time with the movement keys, once
+// for each update no matter how many logic steps it runs
+func (c *Cursor) HandleInput(g *Game) {
+	oldPos := c.Coords
+	tileSize := 7
+
 	// Movement controls
 	if inpututil.IsKeyJustPressed(ebiten.KeyS) {
-		c.Move(image.Pt(0, tileSize))
+		c.step(g, image.Pt(0, tileSize))
 	}
 	if inpututil.IsKeyJustPressed(ebiten.KeyW) {
-		c.Move(image.Pt(0, -tileSize))
+		c.step(g, image.Pt(0, -tileSize))
 	}
 	if inpututil.IsKeyJustPressed(ebiten.KeyA) {
-		c.Move(image.Pt(-tileSize, 0))
+		c.step(g, image.Pt(-tileSize, 0))
 	}
 	if inpututil.IsKeyJustPressed(ebiten.KeyD) {
-		c.Move(image.Pt(tileSize, 0))
+		c.step(g, image.Pt(tileSize, 0))
 	}
 
 	// Keep the cursor inside the map
-	if c.Coords.X < 0 ||
-		c.Coords.Y < hudOffset ||
-		c.Coords.X > g.Size.X ||
-		c.Coords.Y > g.Size.Y {
+	if !insideMap(g, c.Coords) {
 		c.Coords = oldPos
 	}
+}
 
-	return nil
+// Says whether the given coordinates are on the part of the map you can
+// move the cursor around on
+func insideMap(g *Game, coords image.Point) bool {
+	hudOffset := 5
+	mapSize := g.MapSize()
+	return coords.X >= 0 &&
+		coords.Y >= hudOffset &&
+		coords.X <= mapSize.X &&
+		coords.Y <= mapSize.Y
+}
+
+// Move the cursor one tile in the given direction, or in snapping mode to the
+// next tile in that direction that you can build on, if there is one
+func (c *Cursor) step(g *Game, delta image.Point) {
+	if !g.SnapCursor {
+		c.Move(delta)
+		return
+	}
+	for pos := c.Coords.Add(delta); insideMap(g, pos); pos = pos.Add(delta) {
+		if IsBuildable(g, pos) {
+			c.Move(pos.Sub(c.Coords))
+			return
+		}
+	}
 }
 
 // Move moves the player upwards
@@ -72,18 +110,52 @@ func (c *Cursor) Move(dest image.Point) {
 
 // Draw implements Entity
 func (c *Cursor) Draw(g *Game, screen *ebiten.Image) {
-	if c.Cooldown != 0 {
-		return
-	}
-	if !c.BlinkOn {
+	img := c.Image
+	if c.Rejected > 0 {
+		// Flash an X to show it can't be done, even while blinking
+		if (c.Rejected/4)%2 == 0 {
+			return
+		}
+		img = c.SellImage
+	} else if c.Cooldown > 0 {
+		c.drawCooldown(g, screen)
+		if c.BuildAt == c.Coords {
+			return
+		}
+	} else if !c.BlinkOn {
 		return
 	}
+	pos := g.ScreenCoords(c.Coords)
 	op := &ebiten.DrawImageOptions{}
 	op.GeoM.Translate(
-		float64(c.Coords.X-c.Width/2),
-		float64(c.Coords.Y-c.Width/2),
+		float64(pos.X-c.Width/2),
+		float64(pos.Y-c.Width/2),
 	)
-	screen.DrawImage(c.Image, op)
+	screen.DrawImage(img, op)
+}
+
+// StartCooldown hides the cursor for a while to show off the construction
+// animation of a tower built at the given coordinates
+func (c *Cursor) StartCooldown(at image.Point, ticks int) {
+	c.Cooldown = ticks
+	c.CooldownOf = ticks
+	c.BuildAt = at
+}
+
+// Draw a line under the tile that was just built on which shrinks until the
+// cursor comes back, so it's clear why it's gone
+func (c *Cursor) drawCooldown(g *Game, screen *ebiten.Image) {
+	tileSize := 7
+	width := (c.Cooldown*tileSize + c.CooldownOf - 1) / max(1, c.CooldownOf)
+	pos := g.ScreenCoords(c.BuildAt)
+	for x := 0; x < width; x++ {
+		screen.Set(pos.X-tileSize/2+x, pos.Y+tileSize/2+1, ColorDark)
+	}
+}
+
+// Reject flashes the cursor to show that what you tried to do can't be done
+func (c *Cursor) Reject() {
+	c.Rejected = RejectFlashTicks
 }
 
 // NewCursor creates a new cursor struct at the bottom-left of the map
@@ -97,20 +169,55 @@ func NewCursor() *Cursor {
 		5*tileSize+tileCenter+hudOffset,
 	)
 
-	w := 3
-	i := image.NewPaletted(
-		image.Rect(0, 0, w, w),
-		NokiaPalette,
-	)
-	i.Pix = []uint8{
+	c := &Cursor{
+		Coords: coords,
+		Width:  cursorWidth,
+	}
+	c.Recolor()
+	return c
+}
+
+// Width and height of the cursor image
+const cursorWidth = 3
+
+// Pixels of the cursor images, 1 is dark and 0 is see-through
+var (
+	crosshairPixels = []uint8{
+		0, 1, 0,
+		1, 0, 1,
 		0, 1, 0,
+	}
+	sellCursorPixels = []uint8{
 		1, 0, 1,
 		0, 1, 0,
+		1, 0, 1,
 	}
+)
 
-	return &Cursor{
-		Coords: coords,
-		Image:  ebiten.NewImageFromImage(i),
-		Width:  w,
+// Make a cursor image in the current palette
+func newCursorImage(pix []uint8) *ebiten.Image {
+	w := cursorWidth
+	i := image.NewPaletted(
+		image.Rect(0, 0, w, w),
+		NokiaPalette,
+	)
+	i.Pix = pix
+	return ebiten.NewImageFromImage(i)
+}
+
+// Recolor makes the cursor images again in the current palette
+func (c *Cursor) Recolor() {
+	c.CrossImage = newCursorImage(crosshairPixels)
+	c.SellImage = newCursorImage(sellCursorPixels)
+	c.SetSellMode(c.SellMode)
+}
+
+// SetSellMode switches the cursor in or out of sell mode and shows which
+// mode it's in
+func (c *Cursor) SetSellMode(on bool) {
+	c.SellMode = on
+	c.Image = c.CrossImage
+	if on {
+		c.Image = c.SellImage
 	}
 }
@@ -0,0 +1,38 @@
+// Copyright 2022 Siôn le Roux.  All rights reserved.
+// Use of this source code is subject to an MIT-style
+// licence which can be found in the LICENSE file.
+
+package main
+
+import (
+	"image"
+	"testing"
+)
+
+func TestCursorSnapsPastNoBuild(t *testing.T) {
+	tileSize := 7
+	right := image.Pt(tileSize, 0)
+	tests := []struct {
+		name    string
+		snap    bool
+		noBuild NoBuild
+		want    image.Point
+	}{
+		{"over a run of no-build tiles", true, NoBuild{{X: 3, Y: 2}, {X: 4, Y: 2}, {X: 5, Y: 2}}, tileCoords(6, 2)},
+		{"onto a no-build tile without snapping", false, NoBuild{{X: 3, Y: 2}, {X: 4, Y: 2}}, tileCoords(3, 2)},
+		{"with no-build tiles up to the edge", true, NoBuild{{X: 3, Y: 2}, {X: 4, Y: 2}, {X: 5, Y: 2},
+			{X: 6, Y: 2}, {X: 7, Y: 2}, {X: 8, Y: 2}, {X: 9, Y: 2}, {X: 10, Y: 2}, {X: 11, Y: 2}}, tileCoords(2, 2)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := newTestGame()
+			g.SnapCursor = tt.snap
+			g.NoBuild = tt.noBuild
+			g.Cursor.Coords = tileCoords(2, 2)
+			g.Cursor.step(g, right)
+			if g.Cursor.Coords != tt.want {
+				t.Errorf("cursor moved to %v, want %v", g.Cursor.Coords, tt.want)
+			}
+		})
+	}
+}
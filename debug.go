@@ -0,0 +1,88 @@
+// Copyright 2022 Siôn le Roux.  All rights reserved.
+// Use of this source code is subject to an MIT-style
+// licence which can be found in the LICENSE file.
+
+package main
+
+import (
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+// Money you have in sandbox mode, topped up every tick
+const SandboxMoney = 99999
+
+// Settings for testing the game, only available when it's started with the
+// -debug flag
+var debugOptions = []Option{
+	{
+		Name:  "SANDBOX",
+		Value: func(g *Game) string { return onOff(g.Sandbox) },
+		Change: func(g *Game) {
+			g.Sandbox = !g.Sandbox
+		},
+	},
+	{
+		Name:  "WAVES",
+		Value: func(g *Game) string { return "EDIT" },
+		Change: func(g *Game) {
+			g.State = gameStateWaveEditor
+			g.OptionIndex = 0
+		},
+	},
+	{
+		Name:  "MAPS",
+		Value: func(g *Game) string { return "VIEW" },
+		Change: func(g *Game) {
+			g.State = gameStateMapViewer
+			g.ViewMap = g.MapIndex
+		},
+	},
+}
+
+// Describe a setting that can be turned on and off
+func onOff(on bool) string {
+	if on {
+		return "ON"
+	}
+	return "OFF"
+}
+
+// Handle input on the debug menu, which works like the options menu but F1
+// goes back to the game
+func (g *Game) updateDebugMenu() {
+	g.updateMenu(debugOptions)
+	if inpututil.IsKeyJustPressed(ebiten.KeyF1) {
+		g.State = gameStateBuild
+	}
+}
+
+// Draw the debug menu
+func (g *Game) drawDebugMenu(screen *ebiten.Image) {
+	g.drawMenu(screen, "DEBUG", debugOptions)
+}
+
+// Draw how towers pick their targets, with each tower's range outlined, a
+// line to the creep it's attacking and a box around every creep in its range
+func (g *Game) drawTargeting(screen *ebiten.Image) {
+	for _, t := range g.Towers {
+		drawOutline(screen, towerBox(t).Sub(g.Camera), ColorDark)
+		for _, c := range g.Creeps {
+			if t.CanHit(c) && inRange(t, c) {
+				drawOutline(screen, creepBox(c).Sub(g.Camera), ColorDark)
+			}
+		}
+		if t.Target != nil {
+			from := g.ScreenCoords(t.Coords)
+			to := g.ScreenCoords(t.Target.Coords)
+			ebitenutil.DrawLine(screen,
+				float64(from.X),
+				float64(from.Y),
+				float64(to.X),
+				float64(to.Y),
+				ColorDark,
+			)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+// Copyright 2022 Siôn le Roux.  All rights reserved.
+// Use of this source code is subject to an MIT-style
+// licence which can be found in the LICENSE file.
+
+package main
+
+import (
+	"image"
+
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+// Attract mode timing
+const (
+	AttractAfter = 20 * 60 // Idle ticks on the title screen before the demo plays
+	DemoLength   = 60 * 60 // Ticks the demo plays for before going back to the title
+)
+
+// DemoAction is a tower built or upgraded at some point in the demo
+type DemoAction struct {
+	Tick int         // When to do it
+	Tile image.Point // Which tile to build on, upgrading the tower there if there is one
+}
+
+// The recorded demo played in attract mode on the first map
+var attractDemo = []DemoAction{
+	{Tick: 1, Tile: image.Pt(3, 3)},
+	{Tick: 2, Tile: image.Pt(5, 3)},
+	{Tick: 300, Tile: image.Pt(7, 4)},
+	{Tick: 900, Tile: image.Pt(3, 3)},
+	{Tick: 1500, Tile: image.Pt(5, 5)},
+	{Tick: 2100, Tile: image.Pt(5, 3)},
+}
+
+// Where the cursor is when it's over a tile
+func tileCursorCoords(tile image.Point) image.Point {
+	tileSize := 7
+	hudOffset := 6
+	tileCenter := 3
+	return image.Pt(
+		tile.X*tileSize+tileCenter,
+		tile.Y*tileSize+tileCenter+hudOffset,
+	)
+}
+
+// Count how long the title screen has been left alone and start the demo
+// once it's been long enough
+func (g *Game) updateIdle(steps int) {
+	if len(inpututil.AppendJustPressedKeys(nil)) > 0 {
+		g.IdleTicks = 0
+		return
+	}
+	g.IdleTicks += steps
+	if g.IdleTicks >= AttractAfter {
+		g.startDemo()
+	}
+}
+
+// Start playing the demo on the first map from the beginning
+func (g *Game) startDemo() {
+	g.clearRound()
+	g.setMap(0)
+	g.Palette = 0
+	g.State = gameStateDemo
+}
+
+// Stop the demo and go back to the title screen
+func (g *Game) endDemo() {
+	g.clearRound()
+	g.setMap(0)
+	g.IdleTicks = 0
+	g.State = gameStateTitle
+}
+
+// Play the demo, doing each recorded action when its time comes, until it's
+// over or any key is pressed
+func (g *Game) updateDemo(steps int) {
+	if len(inpututil.AppendJustPressedKeys(nil)) > 0 {
+		g.endDemo()
+		return
+	}
+	for i := 0; i < steps && g.State == gameStateDemo; i++ {
+		for _, a := range attractDemo {
+			if a.Tick == g.Tick {
+				g.Cursor.Move(tileCursorCoords(a.Tile).Sub(g.Cursor.Coords))
+				BuyTower(g)
+			}
+		}
+		g.tick()
+	}
+	if g.State != gameStateDemo || g.Tick >= DemoLength {
+		g.endDemo()
+	}
+}
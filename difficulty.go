@@ -0,0 +1,57 @@
+// Copyright 2022 Siôn le Roux.  All rights reserved.
+// Use of this source code is subject to an MIT-style
+// licence which can be found in the LICENSE file.
+
+package main
+
+// Difficulty changes how much money you start each map with
+type Difficulty int
+
+const (
+	difficultyEasy Difficulty = iota
+	difficultyNormal
+	difficultyHard
+	difficultyCount
+)
+
+// String is the name of the difficulty shown on the title screen
+func (d Difficulty) String() string {
+	switch d {
+	case difficultyEasy:
+		return "EASY"
+	case difficultyHard:
+		return "HARD"
+	default:
+		return "NORMAL"
+	}
+}
+
+// DefaultStartingMoney is the amount of money you start a map with if the map
+// doesn't say otherwise
+const DefaultStartingMoney = 500
+
+// How much of a map's starting money you get at each difficulty, in percent
+var difficultyMoneyPercent = map[Difficulty]int{
+	difficultyEasy:   150,
+	difficultyNormal: 100,
+	difficultyHard:   70,
+}
+
+// Work out the money you start a map with from the amount set for the map and
+// the difficulty you're playing at
+func startingMoney(mapMoney int, d Difficulty) int {
+	if mapMoney <= 0 {
+		mapMoney = DefaultStartingMoney
+	}
+	percent, ok := difficultyMoneyPercent[d]
+	if !ok {
+		percent = 100
+	}
+	return mapMoney * percent / 100
+}
+
+// StartingMoney is the money you start the current map with
+func (g *Game) StartingMoney() int {
+	maps := []MapData{g.MapData1, g.MapData2}
+	return startingMoney(maps[g.MapIndex].StartingMoney, g.Difficulty)
+}
@@ -0,0 +1,41 @@
+// Copyright 2022 Siôn le Roux.  All rights reserved.
+// Use of this source code is subject to an MIT-style
+// licence which can be found in the LICENSE file.
+
+package main
+
+import "testing"
+
+func TestResetStartingMoney(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	g := newTestGame()
+	g.MapData2.StartingMoney = 400
+	g.Difficulty = difficultyHard
+	g.Money = 0 // No savings to earn interest on
+
+	g.Reset(true)
+	if g.MapIndex != 1 {
+		t.Fatalf("winning the first map moved on to map %d, want 1", g.MapIndex)
+	}
+	if want := 400 * difficultyMoneyPercent[difficultyHard] / 100; g.Money != want {
+		t.Errorf("started map 2 on hard with %d money, want %d", g.Money, want)
+	}
+}
+
+func TestStartingMoney(t *testing.T) {
+	tests := []struct {
+		mapMoney int
+		d        Difficulty
+		want     int
+	}{
+		{400, difficultyEasy, 600},
+		{400, difficultyNormal, 400},
+		{400, difficultyHard, 280},
+		{0, difficultyNormal, DefaultStartingMoney},
+	}
+	for _, tt := range tests {
+		if got := startingMoney(tt.mapMoney, tt.d); got != tt.want {
+			t.Errorf("startingMoney(%d, %v) = %d, want %d", tt.mapMoney, tt.d, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+// Copyright 2022 Siôn le Roux.  All rights reserved.
+// Use of this source code is subject to an MIT-style
+// licence which can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/text"
+	"golang.org/x/image/font"
+)
+
+// Target dummies soak up damage so you can see how much the towers around
+// them are dealing
+const (
+	DummyHealth = 1000000000 // So much that no tower can kill it in one tick
+	DPSWindow   = 3 * 60     // How many ticks of damage the readout is worked out over
+)
+
+// NewDummyCreep returns a target dummy at the cursor that never moves or dies
+// and shows how much damage per second it's taking, for testing towers
+func NewDummyCreep(g *Game) *Creep {
+	return &Creep{
+		Kind:      creepKindAll,
+		Coords:    g.Cursor.Coords,
+		Health:    DummyHealth,
+		MaxHealth: DummyHealth,
+		Dummy:     true,
+		Sprite:    g.Sprites[spriteSmallMonster],
+	}
+}
+
+// Note how much damage the dummy took since the last tick and heal it back
+// up, keeping only the last few seconds of it
+func (c *Creep) measureDamage() {
+	c.Taken = append(c.Taken, c.MaxHealth-c.Health)
+	if len(c.Taken) > DPSWindow {
+		c.Taken = c.Taken[len(c.Taken)-DPSWindow:]
+	}
+	c.Health = c.MaxHealth
+	if c.HitFlash > 0 {
+		c.HitFlash--
+	}
+}
+
+// DPS is the damage per second the dummy has been taking lately
+func (c *Creep) DPS() int {
+	if len(c.Taken) == 0 {
+		return 0
+	}
+	total := 0
+	for _, d := range c.Taken {
+		total += d
+	}
+	return total * LogicTPS / len(c.Taken)
+}
+
+// Draw how much damage per second the dummy is taking just above it
+func (c *Creep) drawDPS(g *Game, screen *ebiten.Image) {
+	txt := fmt.Sprintf("%d", c.DPS())
+	bounds, _ := font.BoundString(g.TextFont, txt)
+	width := (bounds.Max.X - bounds.Min.X).Ceil()
+	pos := g.ScreenCoords(c.Coords)
+	x := max(0, min(g.Size.X-width, pos.X-width/2))
+	text.Draw(screen, txt, g.TextFont, x, pos.Y-5, ColorDark)
+}
+
+// Says whether any creeps of the wave are still on the map, leaving out
+// target dummies which are never going to go away
+func (g *Game) creepsLeft() bool {
+	return slices.ContainsFunc(g.Creeps, func(c *Creep) bool { return !c.Dummy })
+}
+
+// How many creeps of the wave are on the map, leaving out target dummies
+func (g *Game) creepCount() int {
+	count := 0
+	for _, c := range g.Creeps {
+		if !c.Dummy {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,73 @@
+// Copyright 2022 Siôn le Roux.  All rights reserved.
+// Use of this source code is subject to an MIT-style
+// licence which can be found in the LICENSE file.
+
+package main
+
+import (
+	"errors"
+	"image"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// Returned by an effect's Update once it has finished playing
+var errEffectFinished = errors.New("Effect finished")
+
+// Effect is an animation that plays once somewhere on the map and then goes
+// away, like a heart breaking when a creep reaches the base
+type Effect struct {
+	Coords image.Point // Middle of the animation on the map
+	Sprite *SpriteSheet
+	Animation
+}
+
+// NewEffect makes an effect that plays every frame of a sprite once
+func NewEffect(sprite *SpriteSheet, coords image.Point) *Effect {
+	tag := FrameTag{From: 0, To: len(sprite.Sprite) - 1}
+	return &Effect{
+		Coords:    coords,
+		Sprite:    sprite,
+		Animation: NewAnimation(tag, false),
+	}
+}
+
+// Update moves the animation on, returning an error once it's finished
+func (e *Effect) Update(g *Game) error {
+	if e.Finished() {
+		return errEffectFinished
+	}
+	e.Step(e.Sprite.Sprite)
+	return nil
+}
+
+// Draw draws the current frame of the effect
+func (e *Effect) Draw(g *Game, screen *ebiten.Image) {
+	frame := e.Sprite.Sprite[e.CurrentFrame()]
+	pos := g.ScreenCoords(e.Coords)
+	op := &ebiten.DrawImageOptions{}
+	op.GeoM.Translate(
+		float64(pos.X-frame.Position.W/2),
+		float64(pos.Y-frame.Position.H/2),
+	)
+	screen.DrawImage(e.Sprite.Image.SubImage(image.Rect(
+		frame.Position.X,
+		frame.Position.Y,
+		frame.Position.X+frame.Position.W,
+		frame.Position.Y+frame.Position.H,
+	)).(*ebiten.Image), op)
+}
+
+// Effects is a slice of Effect entities
+type Effects []*Effect
+
+// Move every effect on and forget the ones that have finished
+func (g *Game) updateEffects() {
+	var playing Effects
+	for _, e := range g.Effects {
+		if err := e.Update(g); err == nil {
+			playing = append(playing, e)
+		}
+	}
+	g.Effects = playing
+}
@@ -0,0 +1,29 @@
+// Copyright 2022 Siôn le Roux.  All rights reserved.
+// Use of this source code is subject to an MIT-style
+// licence which can be found in the LICENSE file.
+
+package main
+
+import "testing"
+
+func TestLeakEffect(t *testing.T) {
+	g := newTestGame()
+	path := g.Paths[0]
+	c := NewSmallCreep(g)
+	c.Coords = WaypointCoords(path[len(path)-2])
+	c.NextWaypoint = len(path) - 1
+
+	for i := 0; i < 10000 && !c.Leaked; i++ {
+		if len(g.Effects) != 0 || g.BaseFlash != 0 {
+			t.Fatalf("leak effect shown on tick %d before the creep reached the base at %v", i, c.Coords)
+		}
+		c.Update(g)
+	}
+	if !c.Leaked {
+		t.Fatal("creep never reached the base")
+	}
+	if len(g.Effects) != 1 || g.BaseFlash != BaseFlashTicks {
+		t.Errorf("leak showed %d effects with the base flashing for %d ticks, want 1 and %d",
+			len(g.Effects), g.BaseFlash, BaseFlashTicks)
+	}
+}
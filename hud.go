@@ -0,0 +1,368 @@
+// Copyright 2022 Siôn le Roux.  All rights reserved.
+// Use of this source code is subject to an MIT-style
+// licence which can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"image"
+	"slices"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/hajimehoshi/ebiten/v2/text"
+	"golang.org/x/image/font"
+)
+
+// The HUD is a dark bar across the top of the screen, one line of text high
+const (
+	hudHeight   = 6 // Height of the HUD bar
+	hudBaseline = 5 // Where the bottom of HUD text sits
+	hudPadding  = 1 // Space kept between HUD text and the edges of the screen
+)
+
+// HUDAlign says which part of the HUD bar a piece of text goes in
+type HUDAlign int
+
+const (
+	hudAlignLeft HUDAlign = iota
+	hudAlignCenter
+	hudAlignRight
+)
+
+// Work out the horizontal position of text in the HUD so that it stays on a
+// screen of the given width
+func hudTextX(screenWidth, textWidth int, align HUDAlign) int {
+	switch align {
+	case hudAlignCenter:
+		return (screenWidth - textWidth) / 2
+	case hudAlignRight:
+		return screenWidth - textWidth - hudPadding
+	default:
+		return hudPadding
+	}
+}
+
+// Draw a piece of text into the HUD bar at the given alignment
+func (g *Game) drawHUDText(screen *ebiten.Image, txt string, align HUDAlign) {
+	g.drawBarText(screen, txt, align, hudBaseline)
+}
+
+// Draw a piece of text into a bar at the given alignment and baseline
+func (g *Game) drawBarText(screen *ebiten.Image, txt string, align HUDAlign, baseline int) {
+	bounds, _ := font.BoundString(g.Font, txt)
+	width := (bounds.Max.X - bounds.Min.X).Ceil()
+	text.Draw(screen, txt, g.Font, hudTextX(g.Size.X, width, align), baseline, ColorLight)
+}
+
+// Dim a piece of text in the HUD bar by covering every other pixel of it, to
+// show that it's out of reach
+func (g *Game) dimHUDText(screen *ebiten.Image, txt string, align HUDAlign) {
+	bounds, _ := font.BoundString(g.Font, txt)
+	width := (bounds.Max.X - bounds.Min.X).Ceil()
+	x := hudTextX(g.Size.X, width, align)
+	drawDotted(screen, image.Rect(x, 0, x+width, hudHeight), ColorDark)
+}
+
+// HUDStyle is how money and lives are shown, with icons, numbers or both
+type HUDStyle int
+
+const (
+	hudStyleIcons HUDStyle = iota
+	hudStyleNumbers
+	hudStyleBoth
+	hudStyleCount
+)
+
+// String is the name of the style shown in the options and kept in settings
+func (s HUDStyle) String() string {
+	switch s {
+	case hudStyleNumbers:
+		return "NUMBERS"
+	case hudStyleBoth:
+		return "BOTH"
+	default:
+		return "ICONS"
+	}
+}
+
+// HUDStyle is the way money and lives are shown as chosen in the options,
+// using icons if it was never chosen
+func (g *Game) HUDStyle() HUDStyle {
+	for s := HUDStyle(0); s < hudStyleCount; s++ {
+		if s.String() == g.Settings.HUDStyle {
+			return s
+		}
+	}
+	return hudStyleIcons
+}
+
+// HUDDraws counts the icons and pieces of text drawn for money and lives,
+// which is how tests see what each HUD style shows
+type HUDDraws struct {
+	Icons int
+	Texts int
+}
+
+// Everything drawn for money and lives since the count was last cleared
+var hudDraws HUDDraws
+
+// The first frame of an icon sprite
+func iconImage(s *SpriteSheet) *ebiten.Image {
+	frame := s.Sprite[0]
+	return s.Image.SubImage(image.Rect(
+		frame.Position.X,
+		frame.Position.Y,
+		frame.Position.X+frame.Position.W,
+		frame.Position.Y+frame.Position.H,
+	)).(*ebiten.Image)
+}
+
+// Draw how much money you have on the left of the HUD, after the money icon
+// unless only numbers are wanted
+func (g *Game) drawMoney(screen *ebiten.Image) {
+	txt := fmt.Sprintf("%d", g.Money)
+	if g.HUDStyle() == hudStyleNumbers {
+		g.drawHUDText(screen, "D"+txt, hudAlignLeft)
+		hudDraws.Texts++
+		return
+	}
+	img := iconImage(g.Sprites[spriteIconMoney])
+	size := img.Bounds().Size()
+	op := &ebiten.DrawImageOptions{}
+	op.GeoM.Translate(hudPadding, float64((hudHeight-size.Y)/2))
+	drawSilhouette(screen, img, op.GeoM, ColorLight)
+	text.Draw(screen, txt, g.Font, hudPadding+size.X+1, hudBaseline, ColorLight)
+	hudDraws.Icons++
+	hudDraws.Texts++
+}
+
+// How many ticks a notice stays in the HUD for
+const noticeDuration = 2 * 60
+
+// ShowNotice briefly shows a short message in the middle of the HUD
+func (g *Game) ShowNotice(txt string) {
+	g.Notice = txt
+	g.NoticeTimer = noticeDuration
+}
+
+// Draw the HUD showing money on the left, the cost of building on the right
+// and any notice or what the hovered tower is worth in the middle, with the
+// danger meter underneath
+func (g *Game) drawHUD(screen *ebiten.Image) {
+	ebitenutil.DrawRect(screen, 0, 0, float64(g.Size.X), hudHeight, ColorDark)
+
+	if g.Sandbox {
+		g.drawHUDText(screen, g.T("SANDBOX"), hudAlignLeft)
+	} else {
+		g.drawMoney(screen)
+	}
+
+	hovered := IsOccupied(g, g.Cursor.Coords)
+	if g.State == gameStateDemo {
+		g.drawHUDText(screen, g.T("DEMO"), hudAlignCenter)
+	} else if g.NoticeTimer > 0 {
+		g.drawHUDText(screen, g.Notice, hudAlignCenter)
+	} else if g.WaveCountdown > 0 {
+		g.drawWaveCountdown(screen, g.WaveCountdown)
+	} else if hovered != -1 {
+		g.drawHUDText(screen, towerSummary(g.Towers[hovered], g.Tick), hudAlignCenter)
+	} else if g.WavePhase() == wavePhaseLull {
+		g.drawWaveCountdown(screen, g.NextSpawnIn())
+	} else if g.State == gameStateBuild && g.CanSkipSpawn() {
+		g.drawHUDText(screen, "N>", hudAlignCenter)
+	}
+
+	cost := NewPaletteTower(g).Cost
+	if hovered != -1 {
+		cost = g.Towers[hovered].UpgradeCost(g)
+	}
+	costtxt := g.costText(cost)
+	g.drawHUDText(screen, costtxt, hudAlignRight)
+	if shortfall(g.Money, cost) > 0 {
+		g.dimHUDText(screen, costtxt, hudAlignRight)
+	}
+
+	g.drawDangerMeter(screen)
+	g.drawLives(screen)
+
+	if hovered != -1 {
+		g.drawTowerPanel(screen, g.Towers[hovered])
+	} else if g.Spawned == 0 {
+		g.drawWaveAnnouncement(screen)
+	}
+}
+
+// How many ticks the cost and how much more money you need take turns for
+const shortfallTicks = 60
+
+// How much more money you need to pay a cost, 0 if you can already afford it
+func shortfall(money, cost int) int {
+	return max(0, cost-money)
+}
+
+// Describe the cost of building or upgrading for the right of the HUD, taking
+// turns with how much more money it needs if you can't afford it yet, or MAX
+// for a tower that can't be upgraded, given a cost of -1
+func (g *Game) costText(cost int) string {
+	if cost < 0 {
+		return g.T("MAX")
+	}
+	if short := shortfall(g.Money, cost); short > 0 && (g.Tick/shortfallTicks)%2 == 1 {
+		return fmt.Sprintf("-%d", short)
+	}
+	return fmt.Sprintf("c%d", cost)
+}
+
+// How many ticks each half of the hovered tower's summary is shown for
+const towerSummaryTicks = 2 * 60
+
+// Describe the hovered tower for the middle of the HUD, taking turns between
+// what it's worth and how many kills and how much damage it's made
+func towerSummary(t *Tower, tick int) string {
+	if (tick/towerSummaryTicks)%2 == 0 {
+		return fmt.Sprintf("I%d S%d", t.Invested, t.SellValue())
+	}
+	return fmt.Sprintf("K%d %s", t.Kills, shortNumber(t.Dealt))
+}
+
+// Write a number in as few characters as possible, in thousands once it gets
+// too long to fit in the HUD
+func shortNumber(n int) string {
+	if n < 10000 {
+		return fmt.Sprintf("%d", n)
+	}
+	return fmt.Sprintf("%dK", n/1000)
+}
+
+// Draw a strip along the bottom of the screen with one of each kind of creep
+// coming in the wave, until the first one is sent
+func (g *Game) drawWaveAnnouncement(screen *ebiten.Image) {
+	var sprites []*SpriteSheet
+	for _, c := range g.Waves[g.MapIndex] {
+		if !slices.Contains(sprites, c.Sprite) {
+			sprites = append(sprites, c.Sprite)
+		}
+	}
+	spacing := 8
+	x := (g.Size.X - len(sprites)*spacing) / 2
+	for _, s := range sprites {
+		frame := s.Sprite[0]
+		op := &ebiten.DrawImageOptions{}
+		op.GeoM.Translate(float64(x), float64(g.Size.Y-frame.Position.H-1))
+		screen.DrawImage(s.Image.SubImage(image.Rect(
+			frame.Position.X,
+			frame.Position.Y,
+			frame.Position.X+frame.Position.W,
+			frame.Position.Y+frame.Position.H,
+		)).(*ebiten.Image), op)
+		x += spacing
+	}
+}
+
+// Draw a bar along the bottom of the screen showing the keys to upgrade the
+// hovered tower and what each upgrade costs
+func (g *Game) drawTowerPanel(screen *ebiten.Image, t *Tower) {
+	top := g.Size.Y - hudHeight
+	ebitenutil.DrawRect(screen, 0, float64(top), float64(g.Size.X), hudHeight, ColorDark)
+	txt := ""
+	for tr := UpgradeTrack(0); tr < trackCount; tr++ {
+		if cost := t.TrackCost(tr); cost < 0 {
+			txt += fmt.Sprintf("%d- ", tr+1)
+		} else {
+			txt += fmt.Sprintf("%dc%d ", tr+1, cost)
+		}
+	}
+	g.drawBarText(screen, strings.TrimSpace(txt), hudAlignCenter, top+hudBaseline)
+}
+
+// Draw the seconds left until the wave starts or the next creep comes in the
+// middle of the HUD, next to the time icon
+func (g *Game) drawWaveCountdown(screen *ebiten.Image, ticks int) {
+	seconds := (ticks + LogicTPS - 1) / LogicTPS
+	txt := fmt.Sprintf("%d", seconds)
+	bounds, _ := font.BoundString(g.Font, txt)
+	width := (bounds.Max.X - bounds.Min.X).Ceil()
+
+	s := g.Sprites[spriteIconTime]
+	frame := s.Sprite[0]
+	gap := 1
+	x := (g.Size.X - frame.Position.W - gap - width) / 2
+	op := &ebiten.DrawImageOptions{}
+	op.GeoM.Translate(float64(x), float64((hudHeight-frame.Position.H)/2))
+	drawSilhouette(screen, s.Image.SubImage(image.Rect(
+		frame.Position.X,
+		frame.Position.Y,
+		frame.Position.X+frame.Position.W,
+		frame.Position.Y+frame.Position.H,
+	)).(*ebiten.Image), op.GeoM, ColorLight)
+	text.Draw(screen, txt, g.Font, x+frame.Position.W+gap, hudBaseline, ColorLight)
+}
+
+// How many heart icons to show for your lives in a HUD style, one for each
+// life with only icons, one next to the number with both and none with only
+// numbers
+func lifeIcons(style HUDStyle, lives int) int {
+	switch style {
+	case hudStyleNumbers:
+		return 0
+	case hudStyleBoth:
+		return min(1, lives)
+	default:
+		return lives
+	}
+}
+
+// Draw the lives you have left in the top right corner of the map, just under
+// the HUD, as a heart for each one, a number, or a heart with a number
+func (g *Game) drawLives(screen *ebiten.Image) {
+	img := iconImage(g.Sprites[spriteIconHeart])
+	size := img.Bounds().Size()
+	right := g.Size.X - hudPadding
+	top := hudHeight + 2
+
+	style := g.HUDStyle()
+	if style != hudStyleIcons {
+		txt := fmt.Sprintf("%d", g.Lives)
+		if style == hudStyleNumbers {
+			txt = "L" + txt
+		}
+		bounds, _ := font.BoundString(g.Font, txt)
+		right -= (bounds.Max.X - bounds.Min.X).Ceil()
+		text.Draw(screen, txt, g.Font, right, top+size.Y, ColorDark)
+		hudDraws.Texts++
+		if style == hudStyleNumbers {
+			return
+		}
+		right--
+	}
+
+	spacing := size.X + 1
+	for i := 0; i < lifeIcons(style, g.Lives); i++ {
+		op := &ebiten.DrawImageOptions{}
+		op.GeoM.Translate(float64(right-spacing*(i+1)+1), float64(top))
+		screen.DrawImage(img, op)
+		hudDraws.Icons++
+	}
+}
+
+// Work out how many pixels of the danger meter should be filled, from how far
+// along its path the most advanced creep is
+func dangerFill(creeps Creeps, paths []Ways, width int) int {
+	fill := 0
+	for _, c := range creeps {
+		waypoints := len(paths[c.PathIndex])
+		if waypoints > 1 {
+			fill = max(fill, c.NextWaypoint*width/(waypoints-1))
+		}
+	}
+	return fill
+}
+
+// Draw a thin line under the HUD that grows the closer creeps get to the base
+func (g *Game) drawDangerMeter(screen *ebiten.Image) {
+	fill := dangerFill(g.Creeps, g.Paths, g.Size.X)
+	ebitenutil.DrawRect(screen, 0, hudHeight, float64(fill), 1, ColorDark)
+}
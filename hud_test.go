@@ -0,0 +1,105 @@
+// Copyright 2022 Siôn le Roux.  All rights reserved.
+// Use of this source code is subject to an MIT-style
+// licence which can be found in the LICENSE file.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"golang.org/x/image/font"
+)
+
+func TestHUDTextOnScreen(t *testing.T) {
+	face, err := loadFont("assets/fonts/tiny.ttf", 6)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bounds, _ := font.BoundString(face, "D1000 WAVE 3/4 L5")
+	width := (bounds.Max.X - bounds.Min.X).Ceil()
+
+	for _, screenWidth := range []int{GameSize.X, GameSize.X * 2, GameSize.X * 3} {
+		for _, align := range []HUDAlign{hudAlignLeft, hudAlignCenter, hudAlignRight} {
+			x := hudTextX(screenWidth, width, align)
+			if x < 0 || x+width > screenWidth {
+				t.Errorf("text %d wide aligned %d on a %d wide screen spans %d to %d, off the screen",
+					width, align, screenWidth, x, x+width)
+			}
+		}
+	}
+}
+
+func TestHUDStyles(t *testing.T) {
+	face, err := loadFont("assets/fonts/tiny.ttf", 6)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		style HUDStyle
+		lives int
+		icons int
+		want  HUDDraws
+	}{
+		{hudStyleIcons, 5, 5, HUDDraws{Icons: 1 + 5, Texts: 1}},
+		{hudStyleNumbers, 5, 0, HUDDraws{Icons: 0, Texts: 1 + 1}},
+		{hudStyleBoth, 5, 1, HUDDraws{Icons: 1 + 1, Texts: 1 + 1}},
+		{hudStyleBoth, 0, 0, HUDDraws{Icons: 1, Texts: 1 + 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.style.String(), func(t *testing.T) {
+			g := newTestGame()
+			g.Font = face
+			g.Lives = tt.lives
+			g.Settings.HUDStyle = tt.style.String()
+			if got := g.HUDStyle(); got != tt.style {
+				t.Fatalf("HUDStyle() = %v, want %v", got, tt.style)
+			}
+			if got := lifeIcons(tt.style, tt.lives); got != tt.icons {
+				t.Errorf("lifeIcons(%v, %d) = %d, want %d", tt.style, tt.lives, got, tt.icons)
+			}
+			hudDraws = HUDDraws{}
+			screen := ebiten.NewImage(GameSize.X, GameSize.Y)
+			g.drawMoney(screen)
+			g.drawLives(screen)
+			if hudDraws != tt.want {
+				t.Errorf("drew %+v for money and %d lives, want %+v", hudDraws, tt.lives, tt.want)
+			}
+		})
+	}
+
+	g := newTestGame()
+	g.Settings.HUDStyle = "SPARKLY"
+	if got := g.HUDStyle(); got != hudStyleIcons {
+		t.Errorf("HUDStyle() with an unknown setting = %v, want %v", got, hudStyleIcons)
+	}
+}
+
+func TestShortfall(t *testing.T) {
+	tests := []struct {
+		money int
+		cost  int
+		tick  int
+		short int
+		text  string
+	}{
+		{500, 300, shortfallTicks, 0, "c300"},
+		{300, 300, shortfallTicks, 0, "c300"},
+		{250, 300, 0, 50, "c300"},
+		{250, 300, shortfallTicks, 50, "-50"},
+		{0, 300, shortfallTicks * 3, 300, "-300"},
+		{0, -1, shortfallTicks, 0, "MAX"},
+	}
+	for _, tt := range tests {
+		g := newTestGame()
+		g.Money = tt.money
+		g.Tick = tt.tick
+		if got := shortfall(tt.money, tt.cost); got != tt.short {
+			t.Errorf("shortfall(%d, %d) = %d, want %d", tt.money, tt.cost, got, tt.short)
+		}
+		if got := g.costText(tt.cost); got != tt.text {
+			t.Errorf("costText(%d) with %d money on tick %d = %q, want %q",
+				tt.cost, tt.money, tt.tick, got, tt.text)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+// Copyright 2022 Siôn le Roux.  All rights reserved.
+// Use of this source code is subject to an MIT-style
+// licence which can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"path"
+)
+
+// The language on-screen text is written in, which needs no string table
+const defaultLanguage = "en"
+
+// Strings is a table of on-screen text in another language, keyed by the
+// English text it replaces
+type Strings map[string]string
+
+// Load the string table for a language from assets/lang, there's nothing to
+// load for English
+func loadStrings(code string) (Strings, error) {
+	if code == "" || code == defaultLanguage {
+		return nil, nil
+	}
+	name := path.Join("assets", "lang", code+".json")
+	log.Printf("loading %s\n", name)
+
+	file, err := assets.Open(name)
+	if err != nil {
+		return nil, fmt.Errorf("error opening file %s: %w", name, err)
+	}
+	defer file.Close()
+
+	data, err := ioutil.ReadAll(file)
+	if err != nil {
+		return nil, fmt.Errorf("error reading file %s: %w", name, err)
+	}
+
+	var strings Strings
+	if err := json.Unmarshal(data, &strings); err != nil {
+		return nil, fmt.Errorf("error decoding file %s as JSON: %w", name, err)
+	}
+	return strings, nil
+}
+
+// T translates on-screen text into the language chosen in the settings,
+// leaving it in English if there's no translation for it
+func (g *Game) T(key string) string {
+	if s, ok := g.Strings[key]; ok {
+		return s
+	}
+	return key
+}
@@ -0,0 +1,38 @@
+// Copyright 2022 Siôn le Roux.  All rights reserved.
+// Use of this source code is subject to an MIT-style
+// licence which can be found in the LICENSE file.
+
+package main
+
+import "testing"
+
+func TestTranslate(t *testing.T) {
+	g := newTestGame()
+	tests := []struct {
+		name    string
+		strings Strings
+		key     string
+		want    string
+	}{
+		{"English", nil, "PAUSED", "PAUSED"},
+		{"translated", Strings{"PAUSED": "SZÜNET"}, "PAUSED", "SZÜNET"},
+		{"missing translation", Strings{"PAUSED": "SZÜNET"}, "SANDBOX", "SANDBOX"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g.Strings = tt.strings
+			if got := g.T(tt.key); got != tt.want {
+				t.Errorf("T(%q) = %q, want %q", tt.key, got, tt.want)
+			}
+		})
+	}
+
+	for _, code := range []string{"", defaultLanguage} {
+		if s, err := loadStrings(code); s != nil || err != nil {
+			t.Errorf("loadStrings(%q) = %v, %v, want nothing to load", code, s, err)
+		}
+	}
+	if _, err := loadStrings("xx"); err == nil {
+		t.Error("loadStrings for a language with no file succeeded, want an error")
+	}
+}
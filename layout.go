@@ -0,0 +1,147 @@
+// Copyright 2022 Siôn le Roux.  All rights reserved.
+// Use of this source code is subject to an MIT-style
+// licence which can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"image"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+// Name of the file layout codes are shared through, in the config directory
+const layoutFile = "layout.txt"
+
+// LayoutTower is one tower of a shared layout, just what kind it is and
+// where it goes
+type LayoutTower struct {
+	Kind   TowerKind
+	Coords image.Point
+}
+
+// EncodeLayout turns the kinds and positions of towers into a short code that
+// can be shared, three bytes per tower since the screen is so small
+func EncodeLayout(towers Towers) string {
+	data := make([]byte, 0, len(towers)*3)
+	for _, t := range towers {
+		data = append(data, byte(t.Kind), byte(t.Coords.X), byte(t.Coords.Y))
+	}
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeLayout reads the towers back out of a layout code, returning an error
+// if it isn't one
+func DecodeLayout(code string) ([]LayoutTower, error) {
+	data, err := base64.RawURLEncoding.DecodeString(strings.TrimSpace(code))
+	if err != nil {
+		return nil, fmt.Errorf("decoding layout: %w", err)
+	}
+	if len(data)%3 != 0 {
+		return nil, fmt.Errorf("layout has %d bytes, want a multiple of 3", len(data))
+	}
+	layout := make([]LayoutTower, 0, len(data)/3)
+	for i := 0; i < len(data); i += 3 {
+		kind := TowerKind(data[i])
+		if kind > towerKindBank {
+			return nil, fmt.Errorf("layout has unknown tower kind %d", kind)
+		}
+		layout = append(layout, LayoutTower{
+			Kind:   kind,
+			Coords: image.Pt(int(data[i+1]), int(data[i+2])),
+		})
+	}
+	return layout, nil
+}
+
+// ExportLayout writes a code for the towers on the map to the layout file so
+// it can be shared
+func (g *Game) ExportLayout() error {
+	name, err := configPath(layoutFile)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		return fmt.Errorf("creating layout directory: %w", err)
+	}
+	code := EncodeLayout(g.Towers)
+	if err := os.WriteFile(name, []byte(code+"\n"), 0644); err != nil {
+		return fmt.Errorf("writing layout %s: %w", name, err)
+	}
+	log.Printf("Layout %s written to %s\n", code, name)
+	return nil
+}
+
+// ImportLayout builds the towers of the code in the layout file, skipping any
+// that don't fit on this map or can't be afforded, and says how many it built
+// out of how many there were
+func (g *Game) ImportLayout() (int, int, error) {
+	name, err := configPath(layoutFile)
+	if err != nil {
+		return 0, 0, err
+	}
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return 0, 0, fmt.Errorf("reading layout %s: %w", name, err)
+	}
+	layout, err := DecodeLayout(string(data))
+	if err != nil {
+		return 0, 0, err
+	}
+	built := 0
+	for _, lt := range layout {
+		t := NewTower(g, lt.Kind)
+		t.Coords = snapToTile(lt.Coords)
+		if !HasRoom(g, t.Coords, t.Footprint, -1) || g.Money < t.Cost {
+			continue
+		}
+		t.Invested = t.Cost
+		g.Towers = append(g.Towers, t)
+		g.recordPlacement(t, nil)
+		g.Money -= t.Cost
+		g.TotalSpent += t.Cost
+		built++
+	}
+	return built, len(layout), nil
+}
+
+// Move a point on the map to where the cursor is over the tile it's in, so
+// towers from a code that was edited by hand still line up with the tiles
+func snapToTile(p image.Point) image.Point {
+	tileSize := 7
+	hudMargin := 5
+	return tileCursorCoords(image.Pt(p.X/tileSize, (p.Y-hudMargin)/tileSize))
+}
+
+// Handle the keys for sharing layouts, K writes the code for the towers on the
+// map and J builds the towers of a shared code, only before the wave starts
+func (g *Game) handleLayoutKeys() {
+	if inpututil.IsKeyJustPressed(ebiten.KeyK) {
+		if err := g.ExportLayout(); err != nil {
+			log.Println(err)
+			g.ShowNotice(g.T("NOT SAVED"))
+			return
+		}
+		g.ShowNotice(g.T("SAVED"))
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyJ) {
+		if g.WavePhase() != wavePhaseBuild {
+			g.rejectBuild()
+			return
+		}
+		built, total, err := g.ImportLayout()
+		if err != nil {
+			log.Println(err)
+			g.ShowNotice(g.T("NO LAYOUT"))
+			return
+		}
+		g.ShowNotice(fmt.Sprintf("%d/%d", built, total))
+	}
+}
@@ -0,0 +1,78 @@
+// Copyright 2022 Siôn le Roux.  All rights reserved.
+// Use of this source code is subject to an MIT-style
+// licence which can be found in the LICENSE file.
+
+package main
+
+import (
+	"image"
+	"slices"
+	"testing"
+)
+
+func TestLayoutRoundTrip(t *testing.T) {
+	g := newTestGame()
+	want := []LayoutTower{
+		{towerKindBasic, image.Pt(3, 16)},
+		{towerKindChain, image.Pt(24, 30)},
+		{towerKindBank, image.Pt(80, 44)},
+	}
+	var towers Towers
+	for _, lt := range want {
+		tower := NewTower(g, lt.Kind)
+		tower.Coords = lt.Coords
+		towers = append(towers, tower)
+	}
+
+	code := EncodeLayout(towers)
+	got, err := DecodeLayout(code)
+	if err != nil {
+		t.Fatalf("DecodeLayout(%q) failed: %v", code, err)
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("DecodeLayout(EncodeLayout(towers)) = %v, want %v", got, want)
+	}
+
+	for _, bad := range []string{"not base64!", "AQI", "/wAA"} {
+		if _, err := DecodeLayout(bad); err == nil {
+			t.Errorf("DecodeLayout(%q) succeeded, want an error", bad)
+		}
+	}
+}
+
+func TestImportLayoutSnapsToTiles(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	g := newTestGame()
+	tests := []struct {
+		coords image.Point
+		want   image.Point
+	}{
+		{image.Pt(0, 6), tileCursorCoords(image.Pt(0, 0))},
+		{image.Pt(15, 27), tileCursorCoords(image.Pt(2, 3))},
+		{image.Pt(40, 34), tileCursorCoords(image.Pt(5, 4))},
+	}
+	var towers Towers
+	for _, tt := range tests {
+		tower := NewBasicTower(g)
+		tower.Coords = tt.coords
+		towers = append(towers, tower)
+	}
+	g.Towers = towers
+	if err := g.ExportLayout(); err != nil {
+		t.Fatal(err)
+	}
+	g.Towers = nil
+
+	built, total, err := g.ImportLayout()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if built != total || len(g.Towers) != len(tests) {
+		t.Fatalf("built %d of %d towers, want all %d", built, total, len(tests))
+	}
+	for i, tt := range tests {
+		if got := g.Towers[i].Coords; got != tt.want {
+			t.Errorf("tower coded at %v imported at %v, want on the tile at %v", tt.coords, got, tt.want)
+		}
+	}
+}
@@ -5,11 +5,12 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"image"
 	"image/color"
 	"log"
-	"time"
+	"sync"
 
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/audio"
@@ -32,22 +33,68 @@ var (
 	NokiaPalette color.Palette = color.Palette{ColorTransparent, ColorDark, ColorLight}
 	// GameSize is the screen resolution of a Nokia 3310
 	GameSize image.Point = image.Point{84, 48}
-	// StartingMoney is the amount of money you start the game with
-	StartingMoney int = 500
+)
+
+// Creep spawning timing
+const (
+	SpawnInterval  = 3 * 60  // Default ticks to wait between creeps
+	SkipBonusTicks = 30      // Skipping the wait pays 1 for each this many ticks
+	WaveDelay      = 10 * 60 // Ticks to build before the wave starts
+)
+
+// The wait between creeps runs down faster when the map is nearly empty and
+// slower when it's crowded, so the pressure stays about the same
+const (
+	PaceFewCreeps    = 1   // This many creeps or fewer on the map hurries the next one
+	PaceManyCreeps   = 5   // This many creeps or more on the map holds the next one back
+	FastSpawnPercent = 200 // How fast the wait runs down when hurried, in percent
+	SlowSpawnPercent = 50  // How fast the wait runs down when held back, in percent
+)
+
+// How many creeps can reach the base before you lose
+const StartingLives = 3
+
+// Interest paid on savings at the start of each build phase
+const (
+	InterestRate = 10  // Savings are divided by this, i.e. 10% interest
+	InterestCap  = 100 // The most interest you can get at once
 )
 
 func main() {
-	windowScale := 10
-	ebiten.SetWindowSize(GameSize.X*windowScale, GameSize.Y*windowScale)
+	debug := flag.Bool("debug", false, "enable the debug menu on F1")
+	flag.Parse()
+
 	ebiten.SetWindowTitle("Nokia Defence")
+	ebiten.SetWindowResizingMode(ebiten.WindowResizingModeEnabled)
+	ebiten.SetWindowClosingHandled(true)
 
 	// Fonts
-	font := loadFont("assets/fonts/tiny.ttf", 6)
+	font, err := loadFont("assets/fonts/tiny.ttf", 6)
+	if err != nil {
+		log.Fatal(err)
+	}
 
 	game := &Game{
-		Size:  GameSize,
-		Money: StartingMoney,
-		Font:  font,
+		Size:       GameSize,
+		Difficulty: difficultyNormal,
+		Font:       font,
+		Debug:      *debug,
+	}
+
+	settings, err := LoadSettings()
+	if err != nil {
+		log.Println("Using default settings:", err)
+	}
+	game.Settings = settings
+	game.addTheme()
+	game.SetPalette(palettePreset(game.Settings.Palette))
+	game.SetWindowScale(settings.WindowScale)
+	if err := game.SetTextSize(game.TextSize()); err != nil {
+		log.Fatal(err)
+	}
+	game.Strings, err = loadStrings(settings.Language)
+	if err != nil {
+		log.Println("Using English:", err)
 	}
 
 	go NewGame(game)
@@ -59,26 +106,82 @@ func main() {
 
 // Game represents the main game state
 type Game struct {
-	State         int
-	Size          image.Point
-	Cursor        *Cursor
-	Maps          []*ebiten.Image
-	MapData1      MapData
-	MapData2      MapData
-	Waves         []Creeps
-	MapData       Ways
-	NoBuild       NoBuild // Places where you can't build
-	Sounds        []*audio.Player
-	MapIndex      int
-	Sprites       map[SpriteType]*SpriteSheet
-	Towers        Towers
-	Creeps        Creeps
-	Spawned       int
-	SpawnCooldown int
-	Money         int
-	Count         int
-	TitleFrame    int
-	Font          font.Face
+	State          int
+	Size           image.Point
+	Cursor         *Cursor
+	Maps           []*ebiten.Image
+	MapData1       MapData
+	MapData2       MapData
+	Waves          []Creeps
+	Paths          []Ways  // Paths creeps take from each spawn point to the base
+	NoBuild        NoBuild // Places where you can't build
+	Sounds         []*audio.Player
+	MapMusic       []*audio.Player // Music for each map, nil for the usual music
+	MapIndex       int
+	Sprites        map[SpriteType]*SpriteSheet
+	Towers         Towers
+	Creeps         Creeps
+	Spawned        int
+	SpawnCooldown  int
+	spawnPace      int // Percent of a tick of the spawn cooldown left over from the last tick
+	WaveCountdown  int // Ticks left to build before the wave starts
+	Money          int
+	Lives          int     // How many more creeps can reach the base
+	BaseWear       int     // Hundredths of a life lost to creeps that were hurt
+	Effects        Effects // Animations that play once and go away
+	Coins          Coins   // Loot flying up to the money counter
+	BaseFlash      int     // Ticks left to flash the base for after a leak
+	WinFade        int     // Ticks the screen has been fading out for after a win
+	Count          int
+	Difficulty     Difficulty // How much money you start each map with
+	Seed           uint64     // Seed for shuffling the waves, 0 to keep them in order
+	TitleFrame     int
+	Font           font.Face     // Tiny font for the HUD bar
+	TextFont       font.Face     // Font for messages and menus, at the size chosen in the options
+	Strings        Strings       // On-screen text in the chosen language, empty for English
+	ShowHealthBars bool          // Whether to draw health bars over all creeps
+	ShowGrid       bool          // Whether to draw the build grid over the map
+	ShowRoutes     bool          // Whether to draw the paths creeps take over the map
+	SnapCursor     bool          // Whether the cursor skips tiles you can't build on
+	Palette        int           // Which kind of tower in the build palette to build
+	Placements     []Placement   // Recent tower placements that can be undone
+	BuildQueue     []image.Point // Where to build towers once there's money for them
+	Notice         string        // Short message shown in the HUD
+	NoticeTimer    int           // How many more ticks to show the notice for
+	WaitFrames     int           // How many frames the game has been waiting to be reset
+	GloatTicks     int           // Ticks left to wait after a round before resetting
+	GloatWin       bool          // Whether the round being gloated over was won
+	Tick           int           // Ticks of gameplay since the round started
+	Kills          int           // How many creeps were killed this round
+	Leaks          int           // How many creeps reached the base this round
+	TotalEarned    int           // Money earned this round from kills, bonuses and selling
+	TotalSpent     int           // Money spent this round on towers and upgrades
+	Grade          rune          // Grade for the last map you cleared
+	Summary        RoundStats    // How the last map you cleared went
+	ResumeMusic    bool          // Whether to start the music again after pausing
+	Fades          []*Fade       // Music that's fading in or out
+	Timestep       Timestep      // Keeps the logic running at a fixed rate
+	SlowMotion     bool          // Whether the round is played at half speed
+	TowerMenu      bool          // Whether the menu for the tower under the cursor is open
+	TowerMenuIndex int           // Which action is chosen in the tower menu
+	TutorialStep   int           // Which hint of the first-time tutorial is shown
+	SlowTimestep   Timestep      // Keeps the round running at a fixed rate in slow motion
+	IdleTicks      int           // How long the title screen has been left alone
+	Debug          bool          // Whether the debug menu can be opened
+	ShowTargeting  bool          // Whether to draw how towers pick their targets, for debugging
+	Sandbox        bool          // Unlimited money and creeps can't win, for testing
+	KillStreak     int           // How many creeps were killed in quick succession
+	LastKillTick   int           // When the last creep was killed
+	Camera         image.Point   // Top-left of the part of the map on screen
+	Settings       Settings      // Options kept between runs of the game
+	OptionIndex    int           // Which option is chosen in the options menu
+	ViewMap        int           // Which map is shown in the map viewer
+	QuitFrom       int           // The state to go back to if you don't quit
+	Saved          *GameSnapshot // A game that can be continued from the title screen
+	LoadErrors     []error       // Assets that failed to load, shown instead of the game
+	LoadProgress   float64       // How much of the assets have been loaded, 0..1
+	loaded         bool          // Whether the assets have all loaded and the game is ready
+	loadMutex      sync.Mutex    // Guards loading progress, errors and being loaded
 }
 
 const (
@@ -90,79 +193,184 @@ const (
 	gameStateWin
 	gameStateWaiting
 	gameStatePause
+	gameStateOptions
+	gameStateDebug
+	gameStateWaveEditor
+	gameStateDemo
+	gameStateMapViewer
+	gameStateQuit
+	gameStateOverrun
 )
 
 // NewGame sets up a new game object with default states and game objects
 func NewGame(g *Game) {
 
-	// Music
+	// Assets are listed up front so that loading progress can be shown
+	musicFiles := map[SoundType]string{
+		soundMusicConstruction: "assets/music/construction.ogg",
+		soundMusicTitle:        "assets/music/title.ogg",
+	}
+	soundFiles := map[SoundType]string{
+		soundVictorious: "assets/sfx/victorious.ogg",
+		soundFail:       "assets/sfx/fail.ogg",
+	}
+	spriteFiles := map[SpriteType]string{
+		spriteTowerBasic:         "basic-tower",
+		spriteTowerStrong:        "strong-tower",
+		spriteBigMonsterHorizont: "big_monster_horizont",
+		spriteBigMonsterVertical: "big_monster_vertical",
+		spriteSmallMonster:       "small_monster",
+		spriteTinyMonster:        "tiny_monster",
+		spriteBumm:               "bumm",
+		spriteTowerBottom:        "tower_bottom",
+		spriteTowerLeft:          "tower_left",
+		spriteTowerRight:         "tower_right",
+		spriteTowerUp:            "tower_up",
+		spriteHeartGone:          "heart_gone",
+		spriteIconHeart:          "heart_icon",
+		spriteIconMoney:          "money_icon",
+		spriteIconTime:           "time_icon",
+		spriteTitleScreen:        "titlescreen",
+	}
+	mapImages := []string{
+		"assets/maps/map1.png",
+		"assets/maps/map2.png",
+		"assets/maps/map3.png",
+	}
+	mapWays := []string{"map1", "map2"}
+
 	const sampleRate int = 44100 // assuming "normal" sample rate
-	context := audio.NewContext(sampleRate)
-	g.Sounds = make([]*audio.Player, 4)
-	g.Sounds[soundMusicConstruction] = NewMusicPlayer(loadSoundFile("assets/music/construction.ogg", sampleRate), context)
-	g.Sounds[soundMusicTitle] = NewMusicPlayer(loadSoundFile("assets/music/title.ogg", sampleRate), context)
-	g.Sounds[soundVictorious] = NewSoundPlayer(loadSoundFile("assets/sfx/victorious.ogg", sampleRate), context)
-	g.Sounds[soundFail] = NewSoundPlayer(loadSoundFile("assets/sfx/fail.ogg", sampleRate), context)
-	g.Sounds[soundMusicTitle].Play()
+	load := NewAssetLoader(sampleRate, len(musicFiles)+len(soundFiles)+
+		len(spriteFiles)+len(mapImages)+len(mapWays))
+	load.OnProgress = g.SetLoadProgress
+
+	// Music
+	g.Sounds = make([]*audio.Player, 5)
+	for k, v := range musicFiles {
+		g.Sounds[k] = load.Music(v)
+	}
+	for k, v := range soundFiles {
+		g.Sounds[k] = load.Sound(v)
+	}
+	g.Sounds[soundReject] = load.Beep(110, 80)
 
 	// Sprites
-	g.Sprites = make(map[SpriteType]*SpriteSheet, 12)
-	g.Sprites[spriteTowerBasic] = loadSprite("basic-tower")
-	g.Sprites[spriteTowerStrong] = loadSprite("strong-tower")
-	g.Sprites[spriteBigMonsterHorizont] = loadSprite("big_monster_horizont")
-	g.Sprites[spriteBigMonsterVertical] = loadSprite("big_monster_vertical")
-	g.Sprites[spriteSmallMonster] = loadSprite("small_monster")
-	g.Sprites[spriteTinyMonster] = loadSprite("tiny_monster")
-	g.Sprites[spriteBumm] = loadSprite("bumm")
-	g.Sprites[spriteTowerBottom] = loadSprite("tower_bottom")
-	g.Sprites[spriteTowerLeft] = loadSprite("tower_left")
-	g.Sprites[spriteTowerRight] = loadSprite("tower_right")
-	g.Sprites[spriteTowerUp] = loadSprite("tower_up")
-	g.Sprites[spriteHeartGone] = loadSprite("heart_gone")
-	g.Sprites[spriteIconHeart] = loadSprite("heart_icon")
-	g.Sprites[spriteIconMoney] = loadSprite("money_icon")
-	g.Sprites[spriteIconTime] = loadSprite("time_icon")
-	g.Sprites[spriteTitleScreen] = loadSprite("titlescreen")
+	g.Sprites = make(map[SpriteType]*SpriteSheet, len(spriteFiles))
+	for k, v := range spriteFiles {
+		g.Sprites[k] = load.Sprite(v)
+	}
 
 	// Static images
-	g.Maps = make([]*ebiten.Image, 3)
-	g.Maps[0] = loadImage("assets/maps/map1.png")
-	g.Maps[1] = loadImage("assets/maps/map2.png")
-	g.Maps[2] = loadImage("assets/maps/map3.png")
-	g.MapData1 = loadWays("map1")
-	g.MapData2 = loadWays("map2")
-	g.MapData = g.MapData1.Ways
+	g.Maps = make([]*ebiten.Image, len(mapImages))
+	for k, v := range mapImages {
+		g.Maps[k] = load.Image(v)
+	}
+	g.MapData1 = load.Ways(mapWays[0])
+	g.MapData2 = load.Ways(mapWays[1])
+	g.MapMusic = []*audio.Player{
+		load.OptionalMusic(g.MapData1.Music),
+		load.OptionalMusic(g.MapData2.Music),
+	}
+
+	// Stay on the loading screen and show what went wrong
+	if len(load.Errors) > 0 {
+		g.SetLoadErrors(load.Errors)
+		return
+	}
+
+	g.Paths = g.MapData1.AllPaths()
 	g.NoBuild = g.MapData1.NoBuild
+	g.Money = g.StartingMoney()
 
+	g.Seed = g.newSeed()
 	g.Waves = NewWaves(g)
+	g.WaveCountdown = WaveDelay
+	g.Lives = StartingLives
 	g.Cursor = NewCursor()
 
+	if s, err := LoadSave(); err != nil {
+		log.Println("No game to continue:", err)
+	} else {
+		g.Saved = s
+	}
+
+	g.Sounds[soundMusicTitle].Play()
 	g.State = gameStateTitle
+	g.FinishLoading()
+}
+
+// SetLoadProgress updates the loading progress from the loading goroutine
+func (g *Game) SetLoadProgress(progress float64) {
+	g.loadMutex.Lock()
+	defer g.loadMutex.Unlock()
+	g.LoadProgress = progress
+}
+
+// GetLoadProgress reads the loading progress safely while assets are loading
+func (g *Game) GetLoadProgress() float64 {
+	g.loadMutex.Lock()
+	defer g.loadMutex.Unlock()
+	return g.LoadProgress
+}
+
+// SetLoadErrors records what failed to load from the loading goroutine
+func (g *Game) SetLoadErrors(errs []error) {
+	g.loadMutex.Lock()
+	defer g.loadMutex.Unlock()
+	g.LoadErrors = errs
+}
+
+// GetLoadErrors reads what failed to load safely while assets are loading
+func (g *Game) GetLoadErrors() []error {
+	g.loadMutex.Lock()
+	defer g.loadMutex.Unlock()
+	return g.LoadErrors
+}
+
+// FinishLoading hands the game over from the loading goroutine once it has
+// set everything up, after which the rest of the game can be touched
+func (g *Game) FinishLoading() {
+	g.loadMutex.Lock()
+	defer g.loadMutex.Unlock()
+	g.loaded = true
+}
+
+// Loading says whether the loading goroutine is still setting the game up or
+// failed to, in which case nothing but the loading screen can be touched
+func (g *Game) Loading() bool {
+	g.loadMutex.Lock()
+	defer g.loadMutex.Unlock()
+	return !g.loaded
+}
+
+// Interest earned on savings, rounded down and limited to the cap
+func interest(savings int) int {
+	if savings <= 0 {
+		return 0
+	}
+	return min(InterestCap, savings/InterestRate)
 }
 
 // Reset the game to initial state, ready for a new round
 func (g *Game) Reset(win bool) {
-	g.Creeps = nil
-	g.Towers = nil
-	g.SpawnCooldown = 0
-	g.Spawned = 0
-	g.Waves = NewWaves(g)
-	g.Money = StartingMoney
-	g.Count = 0
-	g.TitleFrame = 0
-	g.Cursor = NewCursor()
+	savings := g.Money
+	music := g.levelMusic()
+	g.clearRound()
+	g.Saved = nil
+	g.Sandbox = false
+	DeleteSave()
 	if win && g.MapIndex < 1 {
 		g.State = gameStateWaiting
-		g.MapData = g.MapData2.Ways
-		g.NoBuild = g.MapData2.NoBuild
-		g.MapIndex++
-		g.Sounds[soundMusicConstruction].Play()
+		g.setMap(g.MapIndex + 1)
+		if bonus := interest(savings); bonus > 0 {
+			g.earn(bonus)
+			g.ShowNotice(fmt.Sprintf("+%d", bonus))
+		}
+		g.Crossfade(music, g.levelMusic())
 		g.State = gameStateBuild
 	} else {
-		g.MapData = g.MapData1.Ways
-		g.NoBuild = g.MapData1.NoBuild
-		g.MapIndex = 0
-		g.Sounds[soundMusicTitle].Play()
+		g.setMap(0)
+		g.Crossfade(music, g.Sounds[soundMusicTitle])
 		if win {
 			g.State = gameStateWon
 		} else {
@@ -171,14 +379,77 @@ func (g *Game) Reset(win bool) {
 	}
 }
 
+// Clear away everything from the last round, ready to start another
+func (g *Game) clearRound() {
+	g.Creeps = nil
+	g.Towers = nil
+	g.Effects = nil
+	g.Coins = nil
+	g.BaseFlash = 0
+	g.WinFade = 0
+	g.Lives = StartingLives
+	g.BaseWear = 0
+	g.Placements = nil
+	g.BuildQueue = nil
+	g.TowerMenu = false
+	g.SpawnCooldown = 0
+	g.WaveCountdown = WaveDelay
+	g.Spawned = 0
+	g.Seed = g.newSeed()
+	g.Waves = NewWaves(g)
+	g.Count = 0
+	g.Tick = 0
+	g.Kills = 0
+	g.Leaks = 0
+	g.TotalEarned = 0
+	g.TotalSpent = 0
+	g.KillStreak = 0
+	g.TitleFrame = 0
+	g.Cursor = NewCursor()
+	g.Camera = image.Point{}
+}
+
+// Switch to one of the maps, with the money you start it with
+func (g *Game) setMap(index int) {
+	maps := []MapData{g.MapData1, g.MapData2}
+	g.MapIndex = index
+	g.Paths = maps[index].AllPaths()
+	g.NoBuild = maps[index].NoBuild
+	g.Money = g.StartingMoney()
+}
+
 // Layout is hardcoded for now, may be made dynamic in future
 func (g *Game) Layout(outsideWidth int, outsideHeight int) (screenWidth int, screenHeight int) {
 	return g.Size.X, g.Size.Y
 }
 
+// DrawFinalScreen scales the game up to the window by the largest whole number
+// that fits so that pixels stay sharp, and letterboxes the rest of the window
+// in the dark colour, which also works for full-screen mode
+func (g *Game) DrawFinalScreen(screen ebiten.FinalScreen, offscreen *ebiten.Image, geoM ebiten.GeoM) {
+	screen.Fill(ColorDark)
+	op := &ebiten.DrawImageOptions{}
+	op.Filter = ebiten.FilterNearest
+	bounds := screen.Bounds()
+	offsize := offscreen.Bounds().Size()
+	scale := min(bounds.Dx()/offsize.X, bounds.Dy()/offsize.Y)
+	if scale < 1 {
+		op.GeoM = geoM // The window is tiny, just squash it in
+	} else {
+		op.GeoM.Scale(float64(scale), float64(scale))
+		op.GeoM.Translate(
+			float64((bounds.Dx()-offsize.X*scale)/2),
+			float64((bounds.Dy()-offsize.Y*scale)/2),
+		)
+	}
+	screen.DrawImage(offscreen, op)
+}
+
 // Update calculates game logic
 func (g *Game) Update() error {
 
+	g.updateZoom()
+
 	// Pressing F toggles full-screen
 	if inpututil.IsKeyJustPressed(ebiten.KeyF) {
 		if ebiten.IsFullscreen() {
@@ -188,8 +459,37 @@ func (g *Game) Update() error {
 		}
 	}
 
-	// Skip updating while the game is loading
-	if g.State == gameStateLoading || g.State == gameStateWaiting {
+	// Skip updating while the game is loading, apart from letting the window
+	// close since there's nothing to save yet
+	if g.Loading() {
+		if ebiten.IsWindowBeingClosed() {
+			return ebiten.Termination
+		}
+		return nil
+	}
+
+	// Save the game in progress when the window is closed
+	if ebiten.IsWindowBeingClosed() {
+		if g.State == gameStateQuit {
+			g.State = g.QuitFrom
+		}
+		g.saveInProgress()
+		return ebiten.Termination
+	}
+
+	steps := g.logicSteps()
+	for i := 0; i < steps; i++ {
+		g.updateFades()
+		if g.WinFade > 0 && g.State == gameStateWaiting {
+			g.WinFade++
+		}
+		g.updateGloat()
+	}
+
+	g.watchWaiting()
+
+	// Skip updating while the game is waiting to reset
+	if g.State == gameStateWaiting {
 		return nil
 	}
 
@@ -199,110 +499,495 @@ func (g *Game) Update() error {
 	}
 
 	if g.State == gameStateLose {
-		g.Sounds[soundMusicConstruction].Pause()
+		g.logMoney()
+		g.levelMusic().Pause()
 		g.Sounds[soundFail].Rewind()
 		g.Sounds[soundFail].Play()
-		g.State = gameStateWaiting
-		gloat := time.NewTimer(time.Second * 4)
-		go func() {
-			log.Println("Gloating")
-			<-gloat.C
-			g.Reset(false)
-		}()
+		g.gloat(4*LogicTPS, false)
+		if g.Settings.Spectate && g.State == gameStateWaiting {
+			g.State = gameStateOverrun
+		}
+		return nil
+	}
+
+	if g.State == gameStateOverrun {
+		g.updateOverrun(steps)
 		return nil
 	}
 
 	if g.State == gameStateWin {
-		g.Sounds[soundMusicConstruction].Pause()
+		g.logMoney()
+		g.Summary = NewRoundStats(g)
+		g.Grade = computeGrade(g.Summary)
+		if g.Sandbox {
+			g.Grade = '-' // Sandbox runs don't count
+		}
+		g.ShowNotice(g.T("GRADE") + " " + string(g.Grade))
+		g.levelMusic().Pause()
 		g.Sounds[soundVictorious].Rewind()
 		g.Sounds[soundVictorious].Play()
-		g.State = gameStateWaiting
-		gloat := time.NewTimer(time.Second * 2)
-		go func() {
-			log.Println("Gloating")
-			<-gloat.C
-			g.Reset(true)
-		}()
+		g.WinFade = 1
+		g.gloat(2*LogicTPS, true)
 		return nil
 	}
 
 	if g.State == gameStateTitle {
-		g.Count = (g.Count + 1) % 15
-		if g.Count == 0 {
-			g.TitleFrame++
-		}
-		if g.TitleFrame > 19 {
-			g.TitleFrame = 16 // XXX copied these from the JSON file cos I'm tired
+		for i := 0; i < steps; i++ {
+			g.Count = (g.Count + 1) % 15
+			if g.Count == 0 {
+				g.TitleFrame++
+			}
+			if g.TitleFrame > 19 {
+				g.TitleFrame = 16 // XXX copied these from the JSON file cos I'm tired
+			}
+			if g.NoticeTimer > 0 {
+				g.NoticeTimer--
+			}
 		}
 		if inpututil.IsKeyJustPressed(ebiten.KeyX) {
 			g.State = gameStateBuild
-			g.Sounds[soundMusicTitle].Pause()
-			g.Sounds[soundMusicConstruction].Play()
+			g.Crossfade(g.Sounds[soundMusicTitle], g.levelMusic())
+		}
+		if inpututil.IsKeyJustPressed(ebiten.KeyA) || inpututil.IsKeyJustPressed(ebiten.KeyD) {
+			step := Difficulty(1)
+			if inpututil.IsKeyJustPressed(ebiten.KeyA) {
+				step = difficultyCount - 1
+			}
+			g.Difficulty = (g.Difficulty + step) % difficultyCount
+			g.Money = g.StartingMoney()
+			g.ShowNotice(g.T(g.Difficulty.String()))
+		}
+		if inpututil.IsKeyJustPressed(ebiten.KeyO) {
+			g.State = gameStateOptions
+		}
+		if inpututil.IsKeyJustPressed(ebiten.KeyEscape) {
+			return ebiten.Termination
+		}
+		g.updateIdle(steps)
+		if inpututil.IsKeyJustPressed(ebiten.KeyC) && g.Saved != nil {
+			if err := g.Restore(g.Saved); err != nil {
+				log.Println("Can't continue saved game:", err)
+			} else {
+				g.State = gameStateBuild
+				g.Crossfade(g.Sounds[soundMusicTitle], g.levelMusic())
+			}
+			g.Saved = nil
 		}
 		return nil
 	}
 
+	if g.State == gameStateOptions {
+		g.updateOptions()
+		return nil
+	}
+
+	if g.State == gameStateDemo {
+		g.updateDemo(steps)
+		return nil
+	}
+
+	if g.State == gameStateDebug {
+		g.updateDebugMenu()
+		return nil
+	}
+	if g.State == gameStateWaveEditor {
+		g.updateWaveEditor()
+		return nil
+	}
+	if g.State == gameStateMapViewer {
+		g.updateMapViewer()
+		return nil
+	}
+	if g.State == gameStateQuit {
+		return g.updateQuit()
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyEscape) {
+		g.askQuit()
+		return nil
+	}
+
+	if g.Debug && inpututil.IsKeyJustPressed(ebiten.KeyF1) {
+		g.State = gameStateDebug
+		return nil
+	}
+	if g.Debug && inpututil.IsKeyJustPressed(ebiten.KeyF2) {
+		g.Creeps = append(g.Creeps, NewDummyCreep(g))
+	}
+	if g.Debug && inpututil.IsKeyJustPressed(ebiten.KeyF3) {
+		g.ShowTargeting = !g.ShowTargeting
+	}
+
 	if g.State == gameStatePause {
 		if inpututil.IsKeyJustPressed(ebiten.KeyZ) {
 			g.State = gameStateBuild
+			if g.ResumeMusic {
+				g.levelMusic().Play()
+			}
 		}
 		return nil
 	}
-	if inpututil.IsKeyJustPressed(ebiten.KeyZ) {
+	if inpututil.IsKeyJustPressed(ebiten.KeyZ) && !ebiten.IsKeyPressed(ebiten.KeyControl) {
 		g.State = gameStatePause
+		music := g.levelMusic()
+		g.ResumeMusic = music.IsPlaying()
+		music.Pause()
 		return nil
 	}
 
+	// The tower menu takes over the keys while it's open
+	if g.TowerMenu {
+		g.updateTowerMenu()
+		g.playRound(steps)
+		return nil
+	}
+
+	// Pressing H toggles health bars over creeps
+	if inpututil.IsKeyJustPressed(ebiten.KeyH) {
+		g.ShowHealthBars = !g.ShowHealthBars
+	}
+
+	// Pressing G toggles the build grid
+	if inpututil.IsKeyJustPressed(ebiten.KeyG) {
+		g.ShowGrid = !g.ShowGrid
+	}
+
+	// Pressing P toggles showing the paths creeps take
+	if inpututil.IsKeyJustPressed(ebiten.KeyP) {
+		g.ShowRoutes = !g.ShowRoutes
+	}
+
+	// Pressing M toggles slow motion
+	if inpututil.IsKeyJustPressed(ebiten.KeyM) {
+		g.SlowMotion = !g.SlowMotion
+		if g.SlowMotion {
+			g.ShowNotice(g.T("SLOW"))
+		} else {
+			g.ShowNotice(g.T("NORMAL"))
+		}
+	}
+
+	// Pressing B toggles snapping the cursor to buildable tiles
+	if inpututil.IsKeyJustPressed(ebiten.KeyB) {
+		g.SnapCursor = !g.SnapCursor
+	}
+
+	g.Cursor.HandleInput(g)
+	g.followCursor()
+
+	// Tower placement controls
+	if inpututil.IsKeyJustPressed(ebiten.KeyX) && !g.Cursor.SellMode {
+		g.buildAtCursor()
+	}
+	// Open the menu of things to do to the tower under the cursor
+	if inpututil.IsKeyJustPressed(ebiten.KeyEnter) {
+		g.openTowerMenu()
+	}
+	// Queue a tower to be built once it can be afforded, or forget the queue
+	if inpututil.IsKeyJustPressed(ebiten.KeyV) {
+		g.ToggleQueued()
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyC) {
+		g.BuildQueue = nil
+	}
+	// Share the layout of towers or build a shared one
+	g.handleLayoutKeys()
+	// Undo the last tower placement
+	if inpututil.IsKeyJustPressed(ebiten.KeyZ) && ebiten.IsKeyPressed(ebiten.KeyControl) {
+		g.Undo()
+	}
+	// Choose which kind of tower to build
+	if inpututil.IsKeyJustPressed(ebiten.KeyE) {
+		g.Palette = (g.Palette + 1) % len(buildPalette)
+		g.ShowNotice(g.T(buildPalette[g.Palette].String()))
+	}
+	// Change how a tower picks its targets
+	if inpututil.IsKeyJustPressed(ebiten.KeyT) {
+		if k := IsOccupied(g, g.Cursor.Coords); k != -1 {
+			g.cycleTargetMode(g.Towers[k])
+		}
+	}
+	// Upgrade one stat of a tower
+	upgradeKeys := []ebiten.Key{ebiten.Key1, ebiten.Key2, ebiten.Key3}
+	for tr, key := range upgradeKeys {
+		if inpututil.IsKeyJustPressed(key) {
+			BuyTrackUpgrade(g, UpgradeTrack(tr))
+		}
+	}
+	// Turn a tower to face another way while it's idle
+	if inpututil.IsKeyJustPressed(ebiten.KeyR) {
+		if k := IsOccupied(g, g.Cursor.Coords); k != -1 {
+			g.Towers[k].Rotate()
+		}
+	}
+	// Lock a tower on to one of the creeps it can reach
+	if inpututil.IsKeyJustPressed(ebiten.KeyL) {
+		if k := IsOccupied(g, g.Cursor.Coords); k != -1 {
+			t := g.Towers[k]
+			t.CycleLock(g)
+			if t.Locked {
+				g.ShowNotice(g.T("LOCK"))
+			} else {
+				g.ShowNotice(g.T("FREE"))
+			}
+		}
+	}
+	// Sell mode makes the action key sell towers instead of building them
+	if inpututil.IsKeyJustPressed(ebiten.KeyQ) {
+		g.Cursor.SetSellMode(!g.Cursor.SellMode)
+	}
+	// Sell a tower
+	if inpututil.IsKeyJustPressed(ebiten.KeyX) && g.Cursor.SellMode {
+		if k := IsOccupied(g, g.Cursor.Coords); k != -1 {
+			g.sellTower(k)
+		}
+	}
+
+	// Send the next creep right away for a bonus
+	if inpututil.IsKeyJustPressed(ebiten.KeyN) && g.CanSkipSpawn() {
+		bonus := g.NextSpawnIn() / SkipBonusTicks
+		g.earn(bonus)
+		g.WaveCountdown = 0
+		g.SpawnCooldown = 0
+		log.Printf("Skipped spawn cooldown for %d bonus\n", bonus)
+	}
+
+	g.updateTutorial()
+	g.playRound(steps)
+
+	return nil
+}
+
+// Play the round on by however many logic steps are due, fewer in slow motion
+func (g *Game) playRound(steps int) {
+	steps = g.playSteps(steps)
+	for i := 0; i < steps && g.State == gameStateBuild; i++ {
+		g.tick()
+	}
+}
+
+// Build or upgrade a tower at the cursor, beeping if it can't be done
+func (g *Game) buildAtCursor() {
+	if result := BuyTower(g); result.Rejected() {
+		log.Println(result)
+		if result != buyRejectedCooldown {
+			g.rejectBuild()
+		}
+	}
+}
+
+// Sell a tower by its index, getting back part of what was spent on it
+func (g *Game) sellTower(k int) {
+	g.earn(g.Towers[k].SellValue())
+	g.Towers = append(g.Towers[:k], g.Towers[k+1:]...)
+	g.forgetPlacements()
+}
+
+// Change how a tower picks its targets to the next mode
+func (g *Game) cycleTargetMode(t *Tower) {
+	t.TargetMode = (t.TargetMode + 1) % targetModeCount
+	g.ShowNotice(g.T(t.TargetMode.String()))
+}
+
+// Run one fixed step of gameplay logic
+func (g *Game) tick() {
+	if g.Sandbox {
+		g.Money = SandboxMoney
+	}
+
+	g.Tick++
 	g.Cursor.Update(g)
 
+	if g.NoticeTimer > 0 {
+		g.NoticeTimer--
+	}
+
+	g.updateBuildQueue()
 	for _, t := range g.Towers {
 		t.Update(g)
 	}
 
-	for i, c := range g.Creeps {
+	var left Creeps
+	for _, c := range g.Creeps {
 		if err := c.Update(g); err != nil {
 			log.Println(err)
-			g.Creeps = append(g.Creeps[:i], g.Creeps[i+1:]...)
+			continue
 		}
+		left = append(left, c)
+	}
+	g.Creeps = left
+
+	g.updateEffects()
+	g.updateCoins()
+	if g.BaseFlash > 0 {
+		g.BaseFlash--
 	}
 
-	if g.Spawned == len(g.Waves[g.MapIndex]) && len(g.Creeps) <= 0 {
+	if g.State == gameStateLose {
+		return
+	}
+
+	if g.WavePhase() == wavePhaseCleared {
 		log.Println("You win")
 		g.State = gameStateWin
+		return
 	}
 
-	// Tower placement controls
-	if inpututil.IsKeyJustPressed(ebiten.KeyX) {
-		BuyTower(g)
+	g.spawnCreeps()
+	g.autoSave()
+}
+
+// Send the next creep in the wave once it's waited long enough after the one
+// before it, once the wave has started
+func (g *Game) spawnCreeps() {
+	wave := g.Waves[g.MapIndex]
+	if g.WaveCountdown > 0 {
+		g.WaveCountdown--
+		return
 	}
-	// Sell a tower
-	if inpututil.IsKeyJustPressed(ebiten.KeyQ) {
-		if k := IsOccupied(g, g.Cursor.Coords); k != -1 {
-			g.Towers = append(g.Towers[:k], g.Towers[k+1:]...)
-			g.Money += 100
-		}
+	if g.SpawnCooldown > 0 {
+		g.spawnPace += spawnPace(g.creepCount())
+		g.SpawnCooldown = max(0, g.SpawnCooldown-g.spawnPace/100)
+		g.spawnPace %= 100
+	}
+	if g.SpawnCooldown > 0 || g.Spawned >= len(wave) {
+		return
 	}
 
-	if g.SpawnCooldown == 0 {
-		spawn := g.MapData[0]
-		gridScale := 7
-		hudMargin := 5
-		gridSquareMid := 4
-		if g.Spawned < len(g.Waves[g.MapIndex]) {
-			creep := g.Waves[g.MapIndex][g.Spawned]
-			creep.Coords = image.Pt(
-				spawn.X*gridScale+gridSquareMid,
-				spawn.Y*gridScale+hudMargin+gridSquareMid,
-			)
-			g.Creeps = append(g.Creeps, creep)
-			g.Spawned++
-		}
+	creep := wave[g.Spawned]
+	creep.PathIndex = g.nextPath()
+	if path := creep.Path(g); len(path) < 2 {
+		log.Printf("Skipping creep %d, path %d is too short to follow\n", g.Spawned, creep.PathIndex)
+		g.Spawned++
+		return
 	}
+	creep.Coords = WaypointCoords(creep.Path(g)[0])
+	g.Creeps = append(g.Creeps, creep)
+	g.Spawned++
+	if g.Spawned < len(wave) {
+		g.SpawnCooldown = wave[g.Spawned].SpawnDelay
+	}
+}
 
-	// Spawn a new creep every N ticks
-	g.SpawnCooldown = (g.SpawnCooldown + 1) % (3 * 60)
+// How fast the wait for the next creep runs down with some creeps on the
+// map, in percent of the normal speed
+func spawnPace(alive int) int {
+	switch {
+	case alive <= PaceFewCreeps:
+		return FastSpawnPercent
+	case alive >= PaceManyCreeps:
+		return SlowSpawnPercent
+	default:
+		return 100
+	}
+}
 
-	return nil
+// Which path the next creep will take, taking turns between them
+func (g *Game) nextPath() int {
+	return g.Spawned % len(g.Paths)
+}
+
+// The music played while building on the current map
+func (g *Game) levelMusic() *audio.Player {
+	if g.MapIndex < len(g.MapMusic) && g.MapMusic[g.MapIndex] != nil {
+		return g.MapMusic[g.MapIndex]
+	}
+	return g.Sounds[soundMusicConstruction]
+}
+
+// Wait a while after the round is over before resetting, unless gloating
+// was turned off in the options, then reset straight away
+func (g *Game) gloat(ticks int, win bool) {
+	if g.Settings.NoGloat {
+		g.Reset(win)
+		return
+	}
+	log.Println("Gloating")
+	g.State = gameStateWaiting
+	g.GloatTicks = ticks
+	g.GloatWin = win
+}
+
+// Count down the wait after a round by a tick, resetting once it's over
+func (g *Game) updateGloat() {
+	if g.GloatTicks <= 0 {
+		return
+	}
+	g.GloatTicks--
+	if g.GloatTicks == 0 {
+		g.Reset(g.GloatWin)
+	}
+}
+
+// How many frames the game can wait for a round to be reset before it's
+// assumed to be stuck, much longer than any gloating
+const WaitTimeout = 10 * 60
+
+// Count how long the game has been waiting after a round for it to be reset
+// with no gloating left to reset it, going back to the title screen if it's
+// been too long in case whatever was meant to reset it never did
+func (g *Game) watchWaiting() {
+	waiting := g.State == gameStateWaiting || g.State == gameStateOverrun
+	if !waiting || g.GloatTicks > 0 {
+		g.WaitFrames = 0
+		return
+	}
+	g.WaitFrames++
+	if g.WaitFrames > WaitTimeout {
+		log.Printf("Waited %d frames to be reset, resetting now\n", g.WaitFrames)
+		g.WaitFrames = 0
+		g.Reset(false)
+	}
+}
+
+// Beep and flash the cursor to show a tower couldn't be bought
+func (g *Game) rejectBuild() {
+	g.Cursor.Reject()
+	if s := g.Sounds[soundReject]; s != nil {
+		s.Rewind()
+		s.Play()
+	}
+}
+
+// NextSpawnIn is how many ticks are left until the next creep is sent,
+// including waiting for the wave to start
+func (g *Game) NextSpawnIn() int {
+	return g.WaveCountdown + g.SpawnCooldown
+}
+
+// WavePhase is how far through the wave the game is
+type WavePhase int
+
+const (
+	wavePhaseBuild   WavePhase = iota // Waiting for the wave to start
+	wavePhaseActive                   // Creeps are on their way to the base
+	wavePhaseLull                     // Every creep sent so far is gone but there are more to come
+	wavePhaseCleared                  // Every creep was sent and none are left
+)
+
+// Work out the phase of a wave from the ticks left before it starts, how many
+// of its creeps have been sent and whether any are still on the map
+func wavePhase(countdown, spawned, waveLength int, creepsLeft bool) WavePhase {
+	switch {
+	case creepsLeft:
+		return wavePhaseActive
+	case spawned >= waveLength:
+		return wavePhaseCleared
+	case countdown > 0 && spawned == 0:
+		return wavePhaseBuild
+	default:
+		return wavePhaseLull
+	}
+}
+
+// WavePhase is how far through the wave being played the game is
+func (g *Game) WavePhase() WavePhase {
+	return wavePhase(g.WaveCountdown, g.Spawned, len(g.Waves[g.MapIndex]), g.creepsLeft())
+}
+
+// CanSkipSpawn says whether there are creeps left to send in this wave that
+// are waiting for the spawn cooldown or the wave to start
+func (g *Game) CanSkipSpawn() bool {
+	return g.NextSpawnIn() > 0 && g.Spawned < len(g.Waves[g.MapIndex])
 }
 
 // Draw draws the game screen by one frame
@@ -310,63 +995,115 @@ func (g *Game) Draw(screen *ebiten.Image) {
 	// Light background
 	screen.Fill(ColorLight)
 
-	if g.State == gameStateLoading {
-		txt := "Loading..."
-		txtf, _ := font.BoundString(g.Font, txt)
+	if g.Loading() {
+		txt := g.T("Loading...")
+		if errs := g.GetLoadErrors(); len(errs) > 0 {
+			txt = fmt.Sprintf(g.T("%d LOAD ERRORS"), len(errs))
+		}
+		txtf, _ := font.BoundString(g.TextFont, txt)
 		txth := (txtf.Max.Y - txtf.Min.Y).Ceil() / 2
 		txtw := (txtf.Max.X - txtf.Min.X).Ceil() / 2
-		text.Draw(screen, txt, g.Font, g.Size.X/2-txtw, g.Size.Y/2-txth, ColorDark)
+		text.Draw(screen, txt, g.TextFont, g.Size.X/2-txtw, g.Size.Y/2-txth, ColorDark)
+
+		// Progress bar
+		barWidth, barHeight := 40.0, 3.0
+		barX := float64(g.Size.X)/2 - barWidth/2
+		barY := float64(g.Size.Y/2 + 2)
+		ebitenutil.DrawRect(screen, barX-1, barY-1, barWidth+2, barHeight+2, ColorDark)
+		ebitenutil.DrawRect(screen, barX, barY, barWidth, barHeight, ColorLight)
+		ebitenutil.DrawRect(screen, barX, barY, barWidth*g.GetLoadProgress(), barHeight, ColorDark)
 		return
 	}
 
 	if g.State == gameStateWon {
-		txt := "YOU WON!"
-		txtf, _ := font.BoundString(g.Font, txt)
+		txt := g.T("YOU WON!")
+		txtf, _ := font.BoundString(g.TextFont, txt)
 		txth := (txtf.Max.Y - txtf.Min.Y).Ceil() / 2
 		txtw := (txtf.Max.X - txtf.Min.X).Ceil() / 2
-		text.Draw(screen, txt, g.Font, g.Size.X/2-txtw, g.Size.Y/2-txth, ColorDark)
+		text.Draw(screen, txt, g.TextFont, g.Size.X/2-txtw, g.Size.Y/2-txth, ColorDark)
+
+		txt = g.T("GRADE") + " " + string(g.Grade)
+		txtf, _ = font.BoundString(g.TextFont, txt)
+		txtw = (txtf.Max.X - txtf.Min.X).Ceil() / 2
+		text.Draw(screen, txt, g.TextFont, g.Size.X/2-txtw, g.Size.Y/2+txth+2, ColorDark)
+
+		txt = fmt.Sprintf("+%d -%d", g.Summary.Earned, g.Summary.Paid)
+		txtf, _ = font.BoundString(g.TextFont, txt)
+		txtw = (txtf.Max.X - txtf.Min.X).Ceil() / 2
+		text.Draw(screen, txt, g.TextFont, g.Size.X/2-txtw, g.Size.Y/2+txth*3+4, ColorDark)
 		return
 	}
 
 	if g.State == gameStatePause {
-		txt := "Paused..."
-		txtf, _ := font.BoundString(g.Font, txt)
+		txt := g.T("Paused...")
+		txtf, _ := font.BoundString(g.TextFont, txt)
 		txth := (txtf.Max.Y - txtf.Min.Y).Ceil() / 2
 		txtw := (txtf.Max.X - txtf.Min.X).Ceil() / 2
-		text.Draw(screen, txt, g.Font, g.Size.X/2-txtw, g.Size.Y/2-txth, ColorDark)
+		text.Draw(screen, txt, g.TextFont, g.Size.X/2-txtw, g.Size.Y/2-txth, ColorDark)
+		return
+	}
+
+	if g.State == gameStateQuit {
+		g.drawQuit(screen)
+		return
+	}
+
+	if g.State == gameStateOptions {
+		g.drawOptions(screen)
+		return
+	}
+
+	if g.State == gameStateDebug {
+		g.drawDebugMenu(screen)
+		return
+	}
+
+	if g.State == gameStateWaveEditor {
+		g.drawWaveEditor(screen)
+		return
+	}
+
+	if g.State == gameStateMapViewer {
+		g.drawMapViewer(screen)
 		return
 	}
 
 	if g.State == gameStateTitle {
 		s := g.Sprites[spriteTitleScreen]
-		frame := s.Sprite[g.TitleFrame]
+		frame := s.Sprite[g.TitleFrame%len(s.Sprite)] // in case of a placeholder
 		screen.DrawImage(s.Image.SubImage(image.Rect(
 			frame.Position.X,
 			frame.Position.Y,
 			frame.Position.X+frame.Position.W,
 			frame.Position.Y+frame.Position.H,
 		)).(*ebiten.Image), &ebiten.DrawImageOptions{})
+		if g.NoticeTimer > 0 {
+			ebitenutil.DrawRect(screen, 0, 0, float64(g.Size.X), hudHeight, ColorDark)
+			g.drawHUDText(screen, g.Notice, hudAlignCenter)
+		} else if g.Saved != nil {
+			ebitenutil.DrawRect(screen, 0, 0, float64(g.Size.X), hudHeight, ColorDark)
+			g.drawHUDText(screen, g.T("C CONTINUE"), hudAlignCenter)
+		}
 		return
 	}
 
 	// Map background image
 	op := &ebiten.DrawImageOptions{}
+	op.GeoM.Translate(float64(-g.Camera.X), float64(-g.Camera.Y))
 	screen.DrawImage(g.Maps[g.MapIndex], op)
 
-	hudSize := 6.0
-	ebitenutil.DrawRect(screen, 0, 0, float64(g.Size.X), hudSize, ColorDark)
-	moneytxt := fmt.Sprintf("D%d", g.Money)
-	text.Draw(screen, moneytxt, g.Font, 1, 5, ColorLight)
-	var cost int
-	if IsOccupied(g, g.Cursor.Coords) != -1 {
-		cost = 300
-	} else {
-		cost = 200
+	g.drawNoBuild(screen)
+	if g.ShowGrid {
+		g.drawGrid(screen)
+	}
+	if g.ShowRoutes {
+		drawRoutes(screen, g.Paths, g.Camera)
 	}
-	costtxt := fmt.Sprintf("c%d", cost)
-	costtxtf, _ := font.BoundString(g.Font, costtxt)
-	costtxtw := (costtxtf.Max.X - costtxtf.Min.X).Ceil()
-	text.Draw(screen, costtxt, g.Font, g.Size.X-costtxtw-1, 5, ColorLight)
+	g.drawSpawnTelegraph(screen)
+	g.drawBuildQueue(screen)
+
+	g.drawHUD(screen)
+	g.drawTutorial(screen)
 
 	for _, t := range g.Towers {
 		t.Draw(g, screen)
@@ -376,7 +1113,22 @@ func (g *Game) Draw(screen *ebiten.Image) {
 		c.Draw(g, screen)
 	}
 
+	for _, e := range g.Effects {
+		e.Draw(g, screen)
+	}
+	for _, c := range g.Coins {
+		c.Draw(g, screen)
+	}
+	g.drawBaseFlash(screen)
+
 	g.Cursor.Draw(g, screen)
+	g.drawTowerMenu(screen)
+
+	if g.ShowTargeting {
+		g.drawTargeting(screen)
+	}
+
+	g.drawWinFade(screen)
 }
 
 // Entity is anything that can be interacted with in the game and drawn  to the
@@ -0,0 +1,343 @@
+// Copyright 2022 Siôn le Roux.  All rights reserved.
+// Use of this source code is subject to an MIT-style
+// licence which can be found in the LICENSE file.
+
+package main
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/audio"
+)
+
+// A sprite sheet with a blank image, with a few frames tagged the way the
+// tower and creep sprites are
+func newTestSprite() *SpriteSheet {
+	frames := make(Frames, 4)
+	for i := range frames {
+		frames[i] = Frame{Duration: 100, Position: FramePosition{W: 7, H: 7}}
+	}
+	return &SpriteSheet{
+		Sprite: frames,
+		Image:  ebiten.NewImage(7, 7),
+		Meta: SpriteMeta{
+			ImageName: "test.png",
+			FrameTags: []FrameTag{
+				{Name: "construction", From: 0, To: 1, Direction: tagForward},
+				{Name: "idle", From: 2, To: 2, Direction: tagForward},
+				{Name: "firing", From: 3, To: 3, Direction: tagForward},
+				{Name: creepTagHorizontal, From: 0, To: 1, Direction: tagForward},
+				{Name: creepTagVertical, From: 2, To: 3, Direction: tagForward},
+			},
+		},
+	}
+}
+
+// A map with one path along the top and down the right, with a no-build tile
+// in the middle
+var testMapData = MapData{
+	Ways:          Ways{{X: 0, Y: 1}, {X: 8, Y: 1}, {X: 8, Y: 5}},
+	NoBuild:       NoBuild{{X: 4, Y: 3}},
+	StartingMoney: 1000,
+	Wave: []WaveSegment{
+		{Creep: "small", Count: 3, Interval: 60},
+	},
+}
+
+// Make a game ready to play a round on a test map without loading any assets,
+// with blank sprites and sounds that play silence
+func newTestGame() *Game {
+	g := &Game{
+		Size:       GameSize,
+		Difficulty: difficultyNormal,
+		MapData1:   testMapData,
+		MapData2:   testMapData,
+		Cursor:     NewCursor(),
+		State:      gameStateBuild,
+		loaded:     true,
+	}
+	g.Sprites = make(map[SpriteType]*SpriteSheet)
+	for s := spriteBigMonster; s <= spriteTitleScreen; s++ {
+		g.Sprites[s] = newTestSprite()
+	}
+	ctx := audio.CurrentContext()
+	if ctx == nil {
+		ctx = audio.NewContext(44100)
+	}
+	g.Sounds = make([]*audio.Player, soundReject+1)
+	for i := range g.Sounds {
+		g.Sounds[i] = ctx.NewPlayerFromBytes(make([]byte, 4))
+	}
+	g.setMap(0)
+	g.Waves = NewWaves(g)
+	g.WaveCountdown = WaveDelay
+	g.Lives = StartingLives
+	return g
+}
+
+func TestSpawnOnEveryPath(t *testing.T) {
+	g := newTestGame()
+	second := Ways{{X: 0, Y: 5}, {X: 8, Y: 5}}
+	g.Paths = []Ways{testMapData.Ways, second}
+	g.WaveCountdown = 0
+
+	for i := 0; i < 1000 && len(g.Creeps) < 2; i++ {
+		g.spawnCreeps()
+	}
+	if len(g.Creeps) < 2 {
+		t.Fatalf("spawned %d creeps, want 2", len(g.Creeps))
+	}
+	for i, c := range g.Creeps[:2] {
+		if c.PathIndex != i {
+			t.Errorf("creep %d took path %d, want %d", i, c.PathIndex, i)
+		}
+		if want := WaypointCoords(g.Paths[i][0]); c.Coords != want {
+			t.Errorf("creep %d spawned at %v, want %v", i, c.Coords, want)
+		}
+	}
+}
+
+func TestSpawnTelegraphShortPath(t *testing.T) {
+	g := newTestGame()
+	g.Paths = []Ways{{}}
+	// Drawing the telegraph for an empty path mustn't panic, halfway through
+	// a blink so the outline would be drawn
+	g.WaveCountdown = 5
+	g.drawSpawnTelegraph(ebiten.NewImage(GameSize.X, GameSize.Y))
+
+	g.WaveCountdown = 0
+	g.spawnCreeps()
+	if g.Spawned != 1 || len(g.Creeps) != 0 {
+		t.Errorf("sent %d creeps with %d on the map down an empty path, want 1 skipped and none on the map",
+			g.Spawned, len(g.Creeps))
+	}
+}
+
+func TestUpdateWhileLoading(t *testing.T) {
+	g := &Game{Size: GameSize}
+	go g.SetLoadProgress(0.5)
+	if err := g.Update(); err != nil {
+		t.Fatalf("Update() while loading = %v, want nil", err)
+	}
+	if !g.Loading() {
+		t.Fatal("Loading() = false before loading finished, want true")
+	}
+	g.FinishLoading()
+	if g.Loading() {
+		t.Error("Loading() = true after loading finished, want false")
+	}
+}
+
+func TestInterest(t *testing.T) {
+	tests := []struct {
+		savings int
+		want    int
+	}{
+		{-100, 0},
+		{0, 0},
+		{InterestRate - 1, 0},
+		{InterestRate, 1},
+		{255, 255 / InterestRate},
+		{InterestCap*InterestRate - 1, InterestCap - 1},
+		{InterestCap * InterestRate, InterestCap},
+		{InterestCap*InterestRate + 5000, InterestCap},
+	}
+	for _, tt := range tests {
+		if got := interest(tt.savings); got != tt.want {
+			t.Errorf("interest(%d) = %d, want %d", tt.savings, got, tt.want)
+		}
+	}
+}
+
+func TestSpawnCadence(t *testing.T) {
+	g := newTestGame()
+	g.MapData1.Wave = []WaveSegment{
+		{Creep: "small", Count: 2, Interval: 30},
+		{Creep: "tiny", Count: 1, Interval: 90},
+	}
+	g.Waves = NewWaves(g)
+	g.WaveCountdown = 0
+	// A couple of creeps already on the map keep the spawn pace normal
+	g.Creeps = Creeps{NewSmallCreep(g), NewSmallCreep(g)}
+
+	var spawnedAt []int
+	for tick := 0; tick < 300; tick++ {
+		before := g.Spawned
+		g.spawnCreeps()
+		if g.Spawned > before {
+			spawnedAt = append(spawnedAt, tick)
+		}
+	}
+	if want := []int{0, 30, 120}; !slices.Equal(spawnedAt, want) {
+		t.Errorf("creeps spawned on ticks %v, want %v", spawnedAt, want)
+	}
+}
+
+func TestWaveCountdown(t *testing.T) {
+	g := newTestGame()
+	g.WaveCountdown = 3
+	for want := 2; want >= 0; want-- {
+		g.spawnCreeps()
+		if g.WaveCountdown != want || len(g.Creeps) != 0 {
+			t.Fatalf("countdown at %d with %d creeps sent, want %d with none", g.WaveCountdown, len(g.Creeps), want)
+		}
+	}
+	g.spawnCreeps()
+	if len(g.Creeps) != 1 {
+		t.Errorf("%d creeps sent when the countdown ran out, want 1", len(g.Creeps))
+	}
+}
+
+func TestBuildRejectionFeedback(t *testing.T) {
+	tests := []struct {
+		name      string
+		setup     func(g *Game)
+		wantFlash bool
+	}{
+		{"built", func(g *Game) {}, false},
+		{"no-build tile", func(g *Game) { g.Cursor.Coords = tileCoords(4, 3) }, true},
+		{"tower can't be upgraded", func(g *Game) { placeTower(g, towerKindStrong, 2, 2) }, true},
+		{"no room to upgrade", func(g *Game) {
+			placeTower(g, towerKindBasic, 2, 2)
+			placeTower(g, towerKindBasic, 3, 2)
+		}, true},
+		{"not enough money", func(g *Game) { g.Money = 0 }, true},
+		{"cursor cooling down", func(g *Game) { g.Cursor.StartCooldown(g.Cursor.Coords, 10) }, false},
+	}
+	reasons := map[string]bool{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := newTestGame()
+			g.Cursor.Coords = tileCoords(2, 2)
+			tt.setup(g)
+			reasons[BuyTower(g).String()] = true
+
+			g = newTestGame()
+			g.Cursor.Coords = tileCoords(2, 2)
+			tt.setup(g)
+			g.buildAtCursor()
+			if flashed := g.Cursor.Rejected > 0; flashed != tt.wantFlash {
+				t.Errorf("cursor flashed %v, want %v", flashed, tt.wantFlash)
+			}
+		})
+	}
+	if len(reasons) != len(tests) {
+		t.Errorf("%d different reasons given for %d different results", len(reasons), len(tests))
+	}
+}
+
+func TestNoGloatResetsNextTick(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	g := newTestGame()
+	g.Settings.NoGloat = true
+	g.State = gameStateLose
+
+	g.Update()
+	if g.State != gameStateTitle {
+		t.Errorf("state %d after losing without gloating, want the title screen %d", g.State, gameStateTitle)
+	}
+
+	g = newTestGame()
+	g.State = gameStateLose
+	g.Update()
+	if g.State != gameStateWaiting || g.GloatTicks <= 0 {
+		t.Errorf("state %d gloating for %d ticks after losing, want waiting %d while gloating",
+			g.State, g.GloatTicks, gameStateWaiting)
+	}
+}
+
+func TestWavePhase(t *testing.T) {
+	tests := []struct {
+		name       string
+		countdown  int
+		spawned    int
+		creepsLeft bool
+		want       WavePhase
+	}{
+		{"counting down", 60, 0, false, wavePhaseBuild},
+		{"countdown over", 0, 0, false, wavePhaseLull},
+		{"creeps on the map", 0, 1, true, wavePhaseActive},
+		{"cleared faster than spawned", 0, 2, false, wavePhaseLull},
+		{"last creep still on the map", 0, 3, true, wavePhaseActive},
+		{"every creep sent and gone", 0, 3, false, wavePhaseCleared},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := wavePhase(tt.countdown, tt.spawned, 3, tt.creepsLeft)
+			if got != tt.want {
+				t.Errorf("wavePhase(%d, %d, 3, %v) = %v, want %v",
+					tt.countdown, tt.spawned, tt.creepsLeft, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSpawnPace(t *testing.T) {
+	tests := []struct {
+		alive int
+		want  int
+	}{
+		{0, FastSpawnPercent},
+		{PaceFewCreeps, FastSpawnPercent},
+		{PaceFewCreeps + 1, 100},
+		{PaceManyCreeps - 1, 100},
+		{PaceManyCreeps, SlowSpawnPercent},
+		{PaceManyCreeps * 3, SlowSpawnPercent},
+	}
+	for _, tt := range tests {
+		if got := spawnPace(tt.alive); got != tt.want {
+			t.Errorf("spawnPace(%d) = %d, want %d", tt.alive, got, tt.want)
+		}
+	}
+}
+
+func TestWatchWaiting(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	g := newTestGame()
+	g.State = gameStateWaiting
+
+	for i := 0; i < WaitTimeout; i++ {
+		g.watchWaiting()
+	}
+	if g.State != gameStateWaiting {
+		t.Fatalf("state %d after waiting %d frames, want still waiting until the timeout", g.State, WaitTimeout)
+	}
+	g.watchWaiting()
+	if g.State != gameStateTitle || g.WaitFrames != 0 {
+		t.Errorf("state %d with %d frames counted after timing out, want back at the title %d",
+			g.State, g.WaitFrames, gameStateTitle)
+	}
+
+	g.State = gameStateWaiting
+	g.GloatTicks = WaitTimeout * 2
+	for i := 0; i <= WaitTimeout; i++ {
+		g.watchWaiting()
+	}
+	if g.State != gameStateWaiting {
+		t.Errorf("state %d after waiting while gloating, want the gloat left to reset it", g.State)
+	}
+}
+
+func TestTwoCreepsDieInOneTick(t *testing.T) {
+	g := newTestGame()
+	alive := NewSmallCreep(g)
+	alive.Coords = WaypointCoords(g.Paths[0][0])
+	var dead Creeps
+	for i := 0; i < 2; i++ {
+		c := NewSmallCreep(g)
+		c.Coords = alive.Coords
+		c.Health = 0
+		dead = append(dead, c)
+	}
+	g.Creeps = Creeps{dead[0], dead[1], alive}
+
+	g.tick()
+	if len(g.Creeps) != 1 || g.Creeps[0] != alive {
+		t.Errorf("%d creeps left after two died in one tick, want just the live one", len(g.Creeps))
+	}
+	if g.Kills != 2 {
+		t.Errorf("counted %d kills, want 2", g.Kills)
+	}
+}
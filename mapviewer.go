@@ -0,0 +1,67 @@
+// Copyright 2022 Siôn le Roux.  All rights reserved.
+// Use of this source code is subject to an MIT-style
+// licence which can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"image"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+// Data for one of the maps by its index, if it has any, since there can be
+// map images without waypoints yet
+func (g *Game) mapDataAt(index int) (MapData, bool) {
+	maps := []MapData{g.MapData1, g.MapData2}
+	if index < 0 || index >= len(maps) {
+		return MapData{}, false
+	}
+	return maps[index], true
+}
+
+// Handle input in the map viewer, A and D go through every map image without
+// playing it and F1 goes back to the debug menu
+func (g *Game) updateMapViewer() {
+	if inpututil.IsKeyJustPressed(ebiten.KeyF1) {
+		g.State = gameStateDebug
+		g.OptionIndex = 0
+		return
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyA) {
+		g.ViewMap = (g.ViewMap + len(g.Maps) - 1) % len(g.Maps)
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyD) {
+		g.ViewMap = (g.ViewMap + 1) % len(g.Maps)
+	}
+}
+
+// Draw the map being viewed with its no-build tiles hatched and the tiles of
+// its waypoints outlined and joined up, for taking screenshots
+func (g *Game) drawMapViewer(screen *ebiten.Image) {
+	if img := g.Maps[g.ViewMap]; img != nil {
+		screen.DrawImage(img, &ebiten.DrawImageOptions{})
+	}
+
+	if data, ok := g.mapDataAt(g.ViewMap); ok {
+		for _, v := range data.NoBuild {
+			drawHatched(screen, noBuildRect(v), ColorDark)
+		}
+		tileSize := 7
+		hudOffset := 5
+		for _, path := range data.AllPaths() {
+			for _, w := range path {
+				corner := image.Pt(w.X*tileSize, w.Y*tileSize+hudOffset)
+				outline := image.Rectangle{corner, corner.Add(image.Pt(tileSize+1, tileSize+1))}
+				drawOutline(screen, outline, ColorDark)
+			}
+		}
+		drawRoutes(screen, data.AllPaths(), image.Point{})
+	}
+
+	ebitenutil.DrawRect(screen, 0, 0, float64(g.Size.X), hudHeight, ColorDark)
+	g.drawHUDText(screen, fmt.Sprintf("MAP %d", g.ViewMap+1), hudAlignCenter)
+}
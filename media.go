@@ -5,11 +5,15 @@
 package main
 
 import (
+	"bytes"
 	"embed"
 	"encoding/json"
+	"fmt"
+	"image"
 	"image/png"
 	"io/ioutil"
 	"log"
+	"math"
 	"path"
 
 	"github.com/hajimehoshi/ebiten/v2"
@@ -22,43 +26,180 @@ import (
 //go:embed assets/*
 var assets embed.FS
 
+// AssetLoader loads game assets and collects any errors along the way, so that
+// a missing or broken file can be reported instead of crashing the game
+type AssetLoader struct {
+	Context    *audio.Context
+	SampleRate int
+	Errors     []error
+	Total      int                    // How many assets are going to be loaded
+	Loaded     int                    // How many assets have been loaded so far
+	OnProgress func(progress float64) // Called after each asset with 0..1
+}
+
+// NewAssetLoader creates an asset loader for a known total number of assets,
+// which plays sounds in a new audio context with the given sample rate
+func NewAssetLoader(sampleRate int, total int) *AssetLoader {
+	return &AssetLoader{
+		Context:    audio.NewContext(sampleRate),
+		SampleRate: sampleRate,
+		Total:      total,
+	}
+}
+
+// Count an asset as loaded, whether it succeeded or not, and report progress
+func (l *AssetLoader) done() {
+	l.Loaded++
+	if l.OnProgress != nil && l.Total > 0 {
+		l.OnProgress(math.Min(1, float64(l.Loaded)/float64(l.Total)))
+	}
+}
+
+// Failed records an error, if there was one, and says whether there was one
+func (l *AssetLoader) Failed(err error) bool {
+	if err == nil {
+		return false
+	}
+	log.Println(err)
+	l.Errors = append(l.Errors, err)
+	return true
+}
+
+// Music loads a music file into a looping player, or nil if loading failed
+func (l *AssetLoader) Music(name string) *audio.Player {
+	defer l.done()
+	stream, err := loadSoundFile(name, l.SampleRate)
+	if l.Failed(err) {
+		return nil
+	}
+	player, err := NewMusicPlayer(stream, l.Context)
+	if l.Failed(err) {
+		return nil
+	}
+	return player
+}
+
+// Sound loads a sound effect file into a player, or nil if loading failed
+func (l *AssetLoader) Sound(name string) *audio.Player {
+	defer l.done()
+	stream, err := loadSoundFile(name, l.SampleRate)
+	if l.Failed(err) {
+		return nil
+	}
+	player, err := NewSoundPlayer(stream, l.Context)
+	if l.Failed(err) {
+		return nil
+	}
+	return player
+}
+
+// Beep makes a short square wave sound effect at the given pitch in hertz,
+// lasting the given number of milliseconds, so it needs no sound file
+func (l *AssetLoader) Beep(pitch, millis int) *audio.Player {
+	samples := l.SampleRate * millis / 1000
+	period := l.SampleRate / pitch
+	volume := int16(math.MaxInt16 / 8)
+	pcm := make([]byte, samples*4) // 16-bit stereo
+	for i := 0; i < samples; i++ {
+		v := volume
+		if i%period < period/2 {
+			v = -volume
+		}
+		for ch := 0; ch < 2; ch++ {
+			pcm[i*4+ch*2] = byte(v)
+			pcm[i*4+ch*2+1] = byte(v >> 8)
+		}
+	}
+	return l.Context.NewPlayerFromBytes(pcm)
+}
+
+// OptionalMusic loads a music file that the game can do without, so it's
+// nil if there's no file given or it can't be loaded, without counting as a
+// loading error
+func (l *AssetLoader) OptionalMusic(name string) *audio.Player {
+	if name == "" {
+		return nil
+	}
+	stream, err := loadSoundFile(name, l.SampleRate)
+	if err != nil {
+		log.Printf("warning: using the usual music instead of %s: %v\n", name, err)
+		return nil
+	}
+	player, err := NewMusicPlayer(stream, l.Context)
+	if err != nil {
+		log.Printf("warning: using the usual music instead of %s: %v\n", name, err)
+		return nil
+	}
+	return player
+}
+
+// Sprite loads a sprite sheet, or a placeholder if loading failed so the game
+// can still run with the missing sprite being obvious on screen
+func (l *AssetLoader) Sprite(name string) *SpriteSheet {
+	defer l.done()
+	sprite, err := loadSprite(name)
+	if err != nil {
+		log.Printf("warning: using placeholder for sprite %s: %v\n", name, err)
+		return placeholderSprite()
+	}
+	return sprite
+}
+
+// Image loads an image, or nil if loading failed
+func (l *AssetLoader) Image(name string) *ebiten.Image {
+	defer l.done()
+	image, err := loadImage(name)
+	if l.Failed(err) {
+		return nil
+	}
+	return image
+}
+
+// Ways loads map waypoint data, or empty data if loading failed
+func (l *AssetLoader) Ways(name string) MapData {
+	defer l.done()
+	mapdata, err := loadWays(name)
+	l.Failed(err)
+	return mapdata
+}
+
 // NewMusicPlayer loads a sound into an audio player that can be used to play it
 // as an infinite loop of music without any additional setup required
-func NewMusicPlayer(music *vorbis.Stream, context *audio.Context) *audio.Player {
+func NewMusicPlayer(music *vorbis.Stream, context *audio.Context) (*audio.Player, error) {
 	musicLoop := audio.NewInfiniteLoop(music, music.Length())
 	musicPlayer, err := audio.NewPlayer(context, musicLoop)
 	if err != nil {
-		log.Fatalf("error making music player: %v\n", err)
+		return nil, fmt.Errorf("error making music player: %w", err)
 	}
-	return musicPlayer
+	return musicPlayer, nil
 }
 
 // NewSoundPlayer loads a sound into an audio player that can be used to play it
 // without any additional setup required
-func NewSoundPlayer(audioFile *vorbis.Stream, context *audio.Context) *audio.Player {
+func NewSoundPlayer(audioFile *vorbis.Stream, context *audio.Context) (*audio.Player, error) {
 	audioPlayer, err := audio.NewPlayer(context, audioFile)
 	if err != nil {
-		log.Fatalf("error making audio player: %v\n", err)
+		return nil, fmt.Errorf("error making audio player: %w", err)
 	}
-	return audioPlayer
+	return audioPlayer, nil
 }
 
 // Load an OGG Vorbis sound file with 44100 sample rate and return its stream
-func loadSoundFile(name string, sampleRate int) *vorbis.Stream {
+func loadSoundFile(name string, sampleRate int) (*vorbis.Stream, error) {
 	log.Printf("loading %s\n", name)
 
 	file, err := assets.Open(name)
 	if err != nil {
-		log.Fatalf("error opening file %s: %v\n", name, err)
+		return nil, fmt.Errorf("error opening file %s: %w", name, err)
 	}
 	defer file.Close()
 
 	music, err := vorbis.DecodeWithSampleRate(sampleRate, file)
 	if err != nil {
-		log.Fatalf("error decoding file %s as Vorbis: %v\n", name, err)
+		return nil, fmt.Errorf("error decoding file %s as Vorbis: %w", name, err)
 	}
 
-	return music
+	return music, nil
 }
 
 // Frame is a single frame of an animation, usually a sub-image of a larger
@@ -77,22 +218,97 @@ type FramePosition struct {
 	H int `json:"h"`
 }
 
-// FrameTags contains tag data about frames to identify different parts of an
+// FrameTag contains tag data about frames to identify different parts of an
 // animation, e.g. idle animation, jump animation frames etc.
-type FrameTags struct {
+type FrameTag struct {
 	Name      string `json:"name"`
 	From      int    `json:"from"`
 	To        int    `json:"to"`
 	Direction string `json:"direction"`
 }
 
+// Ways a tagged part of an animation can be played, as named by Aseprite
+const (
+	tagForward  = "forward"  // From the first frame to the last
+	tagReverse  = "reverse"  // From the last frame to the first
+	tagPingPong = "pingpong" // From the first frame to the last and back again
+)
+
+// Start is the frame the tagged part of the animation is played from
+func (tag FrameTag) Start() int {
+	if tag.Direction == tagReverse {
+		return tag.To
+	}
+	return tag.From
+}
+
+// Next returns the frame that follows the given one within the tagged part of
+// the animation in the direction it's being played, and the direction to
+// play on in, starting over if the given frame is outside of it and at the
+// end looping, turning back for ping-pong tags, or holding on the last frame
+func (tag FrameTag) Next(frame int, backwards, loop bool) (int, bool) {
+	if frame < tag.From || frame > tag.To {
+		return tag.Start(), tag.Direction == tagReverse
+	}
+	if tag.From == tag.To {
+		return frame, backwards
+	}
+	step := 1
+	if backwards {
+		step = -1
+	}
+	if next := frame + step; next >= tag.From && next <= tag.To {
+		return next, backwards
+	}
+	if tag.Direction == tagPingPong {
+		if !backwards {
+			return frame - 1, true
+		}
+		if loop {
+			return frame + 1, false
+		}
+		return frame, backwards
+	}
+	if loop {
+		return tag.Start(), backwards
+	}
+	return frame, backwards
+}
+
 // Frames is a slice of frames used to create sprite animation
 type Frames []Frame
 
+// UnmarshalJSON accepts frames exported by Aseprite either as an array or as a
+// hash keyed by frame name, in which case they are kept in file order
+func (f *Frames) UnmarshalJSON(data []byte) error {
+	var frames []Frame
+	if err := json.Unmarshal(data, &frames); err == nil {
+		*f = frames
+		return nil
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if _, err := dec.Token(); err != nil { // opening brace
+		return err
+	}
+	for dec.More() {
+		if _, err := dec.Token(); err != nil { // frame name
+			return err
+		}
+		var frame Frame
+		if err := dec.Decode(&frame); err != nil {
+			return err
+		}
+		frames = append(frames, frame)
+	}
+	*f = frames
+	return nil
+}
+
 // SpriteMeta contains sprite meta-data, basically everything except frame data
 type SpriteMeta struct {
-	ImageName string      `json:"image"`
-	FrameTags []FrameTags `json:"frameTags"`
+	ImageName string     `json:"image"`
+	FrameTags []FrameTag `json:"frameTags"`
 }
 
 // SpriteSheet is the root-node of sprite data, it contains frames and meta data
@@ -103,6 +319,26 @@ type SpriteSheet struct {
 	Image  *ebiten.Image
 }
 
+// Tag looks up a tagged part of the sprite's animation by its name, saying
+// whether the sprite has it
+func (s *SpriteSheet) Tag(name string) (FrameTag, bool) {
+	for _, t := range s.Meta.FrameTags {
+		if t.Name == name {
+			return t, true
+		}
+	}
+	return FrameTag{}, false
+}
+
+// TagOrAll looks up a tagged part of the sprite's animation by its name,
+// falling back to every frame for sprites that don't have it
+func (s *SpriteSheet) TagOrAll(name string) FrameTag {
+	if tag, ok := s.Tag(name); ok {
+		return tag
+	}
+	return FrameTag{From: 0, To: len(s.Sprite) - 1}
+}
+
 // Waypoint is a point marking a change of direction in the way along the map
 type Waypoint struct {
 	X int `json:"x"`
@@ -115,35 +351,61 @@ type Ways []*Waypoint
 // NoBuild is a slice of points for places you can't build
 type NoBuild []*Waypoint
 
-// MapData is waypoint data for a level map
+// MapData is waypoint and wave data for a level map
 type MapData struct {
-	Ways    Ways    `json:"points"`
-	NoBuild NoBuild `json:"nobuild"`
+	Ways          Ways          `json:"points"`
+	Paths         []Ways        `json:"paths,omitempty"` // More paths from other spawn points
+	NoBuild       NoBuild       `json:"nobuild"`
+	StartingMoney int           `json:"money"`           // Money you start the map with
+	Music         string        `json:"music,omitempty"` // Music file played on the map, if not the usual one
+	Wave          []WaveSegment `json:"wave"`
+}
+
+// AllPaths are all the paths creeps can take on the map, starting with the
+// main one
+func (m MapData) AllPaths() []Ways {
+	return append([]Ways{m.Ways}, m.Paths...)
+}
+
+// Validate checks that creeps can follow every path on the map, which needs
+// a spawn point and at least one more waypoint to head for
+func (m MapData) Validate() error {
+	for i, path := range m.AllPaths() {
+		if len(path) < 2 {
+			return fmt.Errorf("path %d has %d waypoints, it needs at least 2", i, len(path))
+		}
+	}
+	return nil
 }
 
 // Load map waypoint data from a given JSON file
-func loadWays(name string) MapData {
+func loadWays(name string) (MapData, error) {
 	name = path.Join("assets", "maps", name)
 	log.Printf("loading %s\n", name)
 
+	var mapdata MapData
+
 	file, err := assets.Open(name + ".json")
 	if err != nil {
-		log.Fatalf("error opening file %s: %v\n", name, err)
+		return mapdata, fmt.Errorf("error opening file %s: %w", name, err)
 	}
 	defer file.Close()
 
 	data, err := ioutil.ReadAll(file)
 	if err != nil {
-		log.Fatal(err)
+		return mapdata, fmt.Errorf("error reading file %s: %w", name, err)
 	}
 
-	var mapdata MapData
-	json.Unmarshal(data, &mapdata)
+	err = json.Unmarshal(data, &mapdata)
 	if err != nil {
-		log.Fatal(err)
+		return mapdata, fmt.Errorf("error decoding file %s as JSON: %w", name, err)
 	}
 
-	return mapdata
+	if err := mapdata.Validate(); err != nil {
+		return mapdata, fmt.Errorf("invalid map %s: %w", name, err)
+	}
+
+	return mapdata, nil
 }
 
 // SoundType is a unique identifier to reference sound by name
@@ -154,6 +416,7 @@ const (
 	soundMusicConstruction
 	soundVictorious
 	soundFail
+	soundReject
 )
 
 // SpriteType is a unique identifier to load a sprite by name
@@ -181,68 +444,96 @@ const (
 
 // Load a sprite image and associated meta-data given a file name (without
 // extension)
-func loadSprite(name string) *SpriteSheet {
+func loadSprite(name string) (*SpriteSheet, error) {
 	name = path.Join("assets", "sprites", name)
 	log.Printf("loading %s\n", name)
 
 	file, err := assets.Open(name + ".json")
 	if err != nil {
-		log.Fatalf("error opening file %s: %v\n", name, err)
+		return nil, fmt.Errorf("error opening file %s: %w", name, err)
 	}
 	defer file.Close()
 
 	data, err := ioutil.ReadAll(file)
 	if err != nil {
-		log.Fatal(err)
+		return nil, fmt.Errorf("error reading file %s: %w", name, err)
 	}
 
 	var ss SpriteSheet
-	json.Unmarshal(data, &ss)
+	err = json.Unmarshal(data, &ss)
 	if err != nil {
-		log.Fatal(err)
+		return nil, fmt.Errorf("error decoding file %s as JSON: %w", name, err)
+	}
+
+	ss.Image, err = loadImage(name + ".png")
+	if err != nil {
+		return nil, err
+	}
+
+	return &ss, nil
+}
+
+// Generate a single-frame checkerboard sprite the size of a map tile, with
+// frame tags for every animation so it can stand in for any sprite
+func placeholderSprite() *SpriteSheet {
+	const size = 7
+	i := image.NewPaletted(image.Rect(0, 0, size, size), NokiaPalette)
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			i.SetColorIndex(x, y, uint8(1+(x+y)%2))
+		}
 	}
 
-	ss.Image = loadImage(name + ".png")
+	var tags []FrameTag
+	for _, name := range []string{"horizontal_moving", "turn", "vertical_moving"} {
+		tags = append(tags, FrameTag{Name: name, Direction: tagForward})
+	}
 
-	return &ss
+	return &SpriteSheet{
+		Sprite: Frames{{Position: FramePosition{W: size, H: size}}},
+		Meta:   SpriteMeta{FrameTags: tags},
+		Image:  ebiten.NewImageFromImage(i),
+	}
 }
 
 // Load an image from embedded FS into an ebiten Image object
-func loadImage(name string) *ebiten.Image {
+func loadImage(name string) (*ebiten.Image, error) {
 	log.Printf("loading %s\n", name)
 
 	file, err := assets.Open(name)
 	if err != nil {
-		log.Fatalf("error opening file %s: %v\n", name, err)
+		return nil, fmt.Errorf("error opening file %s: %w", name, err)
 	}
 	defer file.Close()
 
 	raw, err := png.Decode(file)
 	if err != nil {
-		log.Fatalf("error decoding file %s as PNG: %v\n", name, err)
+		return nil, fmt.Errorf("error decoding file %s as PNG: %w", name, err)
 	}
 
-	return ebiten.NewImageFromImage(raw)
+	img := ebiten.NewImageFromImage(raw)
+	registerThemedImage(raw, img)
+	return img, nil
 }
 
 // Load a TTF font from a file in  embedded FS into a font face
-func loadFont(name string, size float64) font.Face {
+func loadFont(name string, size float64) (font.Face, error) {
 	log.Printf("loading %s\n", name)
 
 	file, err := assets.Open(name)
 	if err != nil {
-		log.Fatalf("error opening file %s: %v\n", name, err)
+		return nil, fmt.Errorf("error opening file %s: %w", name, err)
 	}
 	defer file.Close()
 
 	data, err := ioutil.ReadAll(file)
 	if err != nil {
-		log.Fatal("error reading font file: ", err)
+		return nil, fmt.Errorf("error reading font file: %w", err)
 	}
 
 	fontdata, err := opentype.Parse(data)
 	if err != nil {
-		log.Fatal("error parsing font data: ", err)
+		return nil, fmt.Errorf("error parsing font data: %w", err)
 	}
 
 	fontface, err := opentype.NewFace(fontdata, &opentype.FaceOptions{
@@ -251,7 +542,7 @@ func loadFont(name string, size float64) font.Face {
 		Hinting: font.HintingFull,
 	})
 	if err != nil {
-		log.Fatal("error creating font face: ", err)
+		return nil, fmt.Errorf("error creating font face: %w", err)
 	}
-	return fontface
+	return fontface, nil
 }
@@ -0,0 +1,175 @@
+// Copyright 2022 Siôn le Roux.  All rights reserved.
+// Use of this source code is subject to an MIT-style
+// licence which can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"testing"
+)
+
+func TestSpriteSheetTag(t *testing.T) {
+	s := newTestSprite()
+
+	tag, ok := s.Tag(towerTagIdle)
+	if !ok || tag.From != 2 || tag.To != 2 {
+		t.Errorf("Tag(%q) = %+v, %v, want frames 2-2", towerTagIdle, tag, ok)
+	}
+	if tag, ok := s.Tag("missing"); ok {
+		t.Errorf("Tag(%q) = %+v, want no tag", "missing", tag)
+	}
+
+	all := s.TagOrAll("missing")
+	if all.From != 0 || all.To != len(s.Sprite)-1 {
+		t.Errorf("TagOrAll(%q) = frames %d-%d, want every frame", "missing", all.From, all.To)
+	}
+}
+
+func TestFrameTagNext(t *testing.T) {
+	// Play a tag from its start for a number of steps, noting each frame
+	play := func(tag FrameTag, loop bool, steps int) []int {
+		frame, backwards := tag.Start(), tag.Direction == tagReverse
+		frames := []int{frame}
+		for i := 0; i < steps; i++ {
+			frame, backwards = tag.Next(frame, backwards, loop)
+			frames = append(frames, frame)
+		}
+		return frames
+	}
+	tests := []struct {
+		name string
+		dir  string
+		loop bool
+		want []int
+	}{
+		{"forward looping", tagForward, true, []int{1, 2, 3, 1, 2, 3, 1}},
+		{"forward once", tagForward, false, []int{1, 2, 3, 3, 3, 3, 3}},
+		{"reverse looping", tagReverse, true, []int{3, 2, 1, 3, 2, 1, 3}},
+		{"reverse once", tagReverse, false, []int{3, 2, 1, 1, 1, 1, 1}},
+		{"pingpong looping", tagPingPong, true, []int{1, 2, 3, 2, 1, 2, 3}},
+		{"pingpong once", tagPingPong, false, []int{1, 2, 3, 2, 1, 1, 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tag := FrameTag{From: 1, To: 3, Direction: tt.dir}
+			got := play(tag, tt.loop, len(tt.want)-1)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("played frames %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMapDataValidate(t *testing.T) {
+	long := Ways{{X: 0, Y: 1}, {X: 8, Y: 1}}
+	tests := []struct {
+		name    string
+		data    MapData
+		wantErr bool
+	}{
+		{"one path", MapData{Ways: long}, false},
+		{"two paths", MapData{Ways: long, Paths: []Ways{long}}, false},
+		{"empty main path", MapData{Ways: Ways{}}, true},
+		{"single-point main path", MapData{Ways: Ways{{X: 0, Y: 1}}}, true},
+		{"empty extra path", MapData{Ways: long, Paths: []Ways{{}}}, true},
+		{"single-point extra path", MapData{Ways: long, Paths: []Ways{{{X: 3, Y: 3}}}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.data.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadMissingAsset(t *testing.T) {
+	if _, err := loadSprite("missing"); err == nil {
+		t.Error("loadSprite of a missing sprite succeeded, want an error")
+	}
+	if _, err := loadImage("assets/missing.png"); err == nil {
+		t.Error("loadImage of a missing image succeeded, want an error")
+	}
+	if _, err := loadSoundFile("assets/missing.ogg", 44100); err == nil {
+		t.Error("loadSoundFile of a missing sound succeeded, want an error")
+	}
+	if _, err := loadWays("missing"); err == nil {
+		t.Error("loadWays of a missing map succeeded, want an error")
+	}
+	if _, err := loadFont("assets/missing.ttf", 6); err == nil {
+		t.Error("loadFont of a missing font succeeded, want an error")
+	}
+
+	l := &AssetLoader{SampleRate: 44100, Total: 2}
+	if img := l.Image("assets/missing.png"); img != nil {
+		t.Error("loader returned an image for a missing file, want nil")
+	}
+	l.Ways("missing")
+	if len(l.Errors) != 2 || l.Loaded != 2 {
+		t.Errorf("loader collected %d errors from %d assets, want 2 from 2", len(l.Errors), l.Loaded)
+	}
+}
+
+func TestPlaceholderForMissingSprite(t *testing.T) {
+	l := &AssetLoader{Total: 1}
+	s := l.Sprite("missing")
+	if s == nil || s.Image == nil || len(s.Sprite) == 0 {
+		t.Fatalf("loader returned %+v for a missing sprite, want a usable placeholder", s)
+	}
+	if _, ok := clampFrame(s, s.TagOrAll(creepTagHorizontal).To); !ok {
+		t.Error("placeholder has no frame to draw for its animations")
+	}
+	if len(l.Errors) != 0 {
+		t.Errorf("missing sprite counted as %d loading errors, want 0", len(l.Errors))
+	}
+}
+
+func TestDecodeSpriteSheet(t *testing.T) {
+	const sample = `{
+		"frames": %s,
+		"meta": {
+			"image": "sample.png",
+			"frameTags": [
+				{"name": "walk", "from": 0, "to": 1, "direction": "pingpong"}
+			]
+		}
+	}`
+	tests := []struct {
+		name   string
+		frames string
+	}{
+		{"frames as an array", `[
+			{"frame": {"x": 0, "y": 0, "w": 5, "h": 8}, "duration": 250},
+			{"frame": {"x": 5, "y": 0, "w": 5, "h": 8}, "duration": 100}
+		]`},
+		{"frames as a hash", `{
+			"sample 0.aseprite": {"frame": {"x": 0, "y": 0, "w": 5, "h": 8}, "duration": 250},
+			"sample 1.aseprite": {"frame": {"x": 5, "y": 0, "w": 5, "h": 8}, "duration": 100}
+		}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var s SpriteSheet
+			if err := json.Unmarshal([]byte(fmt.Sprintf(sample, tt.frames)), &s); err != nil {
+				t.Fatal(err)
+			}
+			want := Frames{
+				{Duration: 250, Position: FramePosition{X: 0, W: 5, H: 8}},
+				{Duration: 100, Position: FramePosition{X: 5, W: 5, H: 8}},
+			}
+			if !slices.Equal(s.Sprite, want) {
+				t.Errorf("decoded frames %+v, want %+v", s.Sprite, want)
+			}
+			if s.Meta.ImageName != "sample.png" {
+				t.Errorf("decoded image name %q, want %q", s.Meta.ImageName, "sample.png")
+			}
+			wantTag := FrameTag{Name: "walk", From: 0, To: 1, Direction: tagPingPong}
+			if tag, ok := s.Tag("walk"); !ok || tag != wantTag {
+				t.Errorf("decoded tag %+v, want %+v", tag, wantTag)
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+// Copyright 2022 Siôn le Roux.  All rights reserved.
+// Use of this source code is subject to an MIT-style
+// licence which can be found in the LICENSE file.
+
+package main
+
+import (
+	"slices"
+
+	"github.com/hajimehoshi/ebiten/v2/audio"
+)
+
+// How many ticks it takes to fade from one piece of music to another
+const CrossfadeTicks = 30
+
+// Fade changes the volume of a player over time, pausing it at the end if it
+// fades out
+type Fade struct {
+	Player *audio.Player
+	From   float64 // Volume at the start of the fade
+	To     float64 // Volume at the end of the fade
+	Tick   int     // How far through the fade it is
+}
+
+// Crossfade fades out one player while starting another and fading it in
+func (g *Game) Crossfade(from, to *audio.Player) {
+	g.Fades = slices.DeleteFunc(g.Fades, func(f *Fade) bool {
+		return f.Player == from || f.Player == to
+	})
+	if from.IsPlaying() {
+		g.Fades = append(g.Fades, &Fade{Player: from, From: from.Volume(), To: 0})
+	}
+	to.SetVolume(0)
+	to.Play()
+	g.Fades = append(g.Fades, &Fade{Player: to, From: 0, To: 1})
+}
+
+// Move each fade on by a tick and forget the ones that are finished
+func (g *Game) updateFades() {
+	g.Fades = slices.DeleteFunc(g.Fades, func(f *Fade) bool {
+		f.Tick++
+		progress := float64(f.Tick) / CrossfadeTicks
+		f.Player.SetVolume(f.From + (f.To-f.From)*min(1, progress))
+		if f.Tick < CrossfadeTicks {
+			return false
+		}
+		if f.To == 0 {
+			f.Player.Pause()
+			f.Player.SetVolume(1)
+		}
+		return true
+	})
+}
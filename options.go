@@ -0,0 +1,131 @@
+// Copyright 2022 Siôn le Roux.  All rights reserved.
+// Use of this source code is subject to an MIT-style
+// licence which can be found in the LICENSE file.
+
+package main
+
+import (
+	"log"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/hajimehoshi/ebiten/v2/text"
+)
+
+// Option is one line of the options menu
+type Option struct {
+	Name   string
+	Value  func(g *Game) string // What the option is set to now
+	Change func(g *Game)        // Switch the option to its next setting
+}
+
+// The options you can change from the title screen
+var options = []Option{
+	{
+		Name:  "COLOURS",
+		Value: func(g *Game) string { return g.Settings.Palette },
+		Change: func(g *Game) {
+			g.SetPalette((palettePreset(g.Settings.Palette) + 1) % len(palettePresets))
+		},
+	},
+	{
+		Name:  "HUD",
+		Value: func(g *Game) string { return g.HUDStyle().String() },
+		Change: func(g *Game) {
+			g.Settings.HUDStyle = ((g.HUDStyle() + 1) % hudStyleCount).String()
+		},
+	},
+	{
+		Name:  "LAST STAND",
+		Value: func(g *Game) string { return onOff(g.Settings.LastStand) },
+		Change: func(g *Game) {
+			g.Settings.LastStand = !g.Settings.LastStand
+		},
+	},
+	{
+		Name:  "SHUFFLE",
+		Value: func(g *Game) string { return onOff(g.Settings.Shuffle) },
+		Change: func(g *Game) {
+			g.Settings.Shuffle = !g.Settings.Shuffle
+			g.Seed = g.newSeed()
+			g.Waves = NewWaves(g)
+		},
+	},
+	{
+		Name:  "GLOAT",
+		Value: func(g *Game) string { return onOff(!g.Settings.NoGloat) },
+		Change: func(g *Game) {
+			g.Settings.NoGloat = !g.Settings.NoGloat
+		},
+	},
+	{
+		Name:  "TEXT",
+		Value: func(g *Game) string { return g.TextSize().String() },
+		Change: func(g *Game) {
+			if err := g.SetTextSize((g.TextSize() + 1) % textSizeCount); err != nil {
+				log.Println("Changing text size failed:", err)
+			}
+		},
+	},
+	{
+		Name:  "WATCH",
+		Value: func(g *Game) string { return onOff(g.Settings.Spectate) },
+		Change: func(g *Game) {
+			g.Settings.Spectate = !g.Settings.Spectate
+		},
+	},
+}
+
+// Handle input on the options menu, W and S choose an option, X changes it
+// and O goes back to the title screen
+func (g *Game) updateOptions() {
+	if g.updateMenu(options) {
+		if err := g.Settings.Save(); err != nil {
+			log.Println("Saving settings failed:", err)
+		}
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyO) {
+		g.State = gameStateTitle
+	}
+}
+
+// Draw the options menu, with the chosen option marked
+func (g *Game) drawOptions(screen *ebiten.Image) {
+	g.drawMenu(screen, "OPTIONS", options)
+}
+
+// Handle input on a menu, W and S choose an option and X changes it, saying
+// whether anything was changed
+func (g *Game) updateMenu(menu []Option) bool {
+	if inpututil.IsKeyJustPressed(ebiten.KeyW) {
+		g.OptionIndex = (g.OptionIndex + len(menu) - 1) % len(menu)
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyS) {
+		g.OptionIndex = (g.OptionIndex + 1) % len(menu)
+	}
+	g.OptionIndex %= len(menu)
+	if inpututil.IsKeyJustPressed(ebiten.KeyX) {
+		menu[g.OptionIndex].Change(g)
+		return true
+	}
+	return false
+}
+
+// Draw a menu with its title in a bar at the top and the chosen option marked,
+// scrolling down to it if they don't all fit
+func (g *Game) drawMenu(screen *ebiten.Image, title string, menu []Option) {
+	ebitenutil.DrawRect(screen, 0, 0, float64(g.Size.X), hudHeight, ColorDark)
+	g.drawHUDText(screen, g.T(title), hudAlignCenter)
+	lineHeight := g.textLineHeight()
+	lines := (g.Size.Y - hudHeight) / lineHeight
+	first := max(0, g.OptionIndex-lines+1)
+	for i := first; i < min(len(menu), first+lines); i++ {
+		y := hudHeight + lineHeight*(i-first+1)
+		txt := g.T(menu[i].Name) + " " + g.T(menu[i].Value(g))
+		if i == g.OptionIndex {
+			txt = ">" + txt
+		}
+		text.Draw(screen, txt, g.TextFont, hudPadding, y, ColorDark)
+	}
+}
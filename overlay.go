@@ -0,0 +1,189 @@
+// Copyright 2022 Siôn le Roux.  All rights reserved.
+// Use of this source code is subject to an MIT-style
+// licence which can be found in the LICENSE file.
+
+package main
+
+import (
+	"image"
+	"image/color"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/colorm"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+)
+
+// Draw every other pixel of a rectangle, which looks like a faint version of
+// the colour on a 1-bit screen
+func drawDotted(screen *ebiten.Image, r image.Rectangle, clr color.Color) {
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			if (x+y)%2 == 0 {
+				screen.Set(x, y, clr)
+			}
+		}
+	}
+}
+
+// Draw an image as a silhouette in one colour, keeping its shape
+func drawSilhouette(screen, img *ebiten.Image, geoM ebiten.GeoM, clr color.Color) {
+	var cm colorm.ColorM
+	cm.Scale(0, 0, 0, 1)
+	r, g, b, _ := clr.RGBA()
+	cm.Translate(float64(r)/0xffff, float64(g)/0xffff, float64(b)/0xffff, 0)
+	colorm.DrawImage(screen, img, cm, &colorm.DrawImageOptions{GeoM: geoM})
+}
+
+// Draw sparse diagonal lines across a rectangle, fainter than dotting it
+func drawHatched(screen *ebiten.Image, r image.Rectangle, clr color.Color) {
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			if (x+y)%4 == 0 {
+				screen.Set(x, y, clr)
+			}
+		}
+	}
+}
+
+// Draw the outline of a rectangle one pixel thick
+func drawOutline(screen *ebiten.Image, r image.Rectangle, clr color.Color) {
+	for x := r.Min.X; x < r.Max.X; x++ {
+		screen.Set(x, r.Min.Y, clr)
+		screen.Set(x, r.Max.Y-1, clr)
+	}
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		screen.Set(r.Min.X, y, clr)
+		screen.Set(r.Max.X-1, y, clr)
+	}
+}
+
+// Draw faint lines along the edges of the build tiles, with the tile under the
+// cursor outlined more strongly
+func (g *Game) drawGrid(screen *ebiten.Image) {
+	tileSize := 7
+	hudOffset := 5
+	mapSize := g.MapSize()
+
+	for x := 0; x <= mapSize.X; x += tileSize {
+		line := image.Rect(x, 0, x+1, mapSize.Y).Sub(g.Camera)
+		drawDotted(screen, line, ColorDark)
+	}
+	for y := hudOffset; y <= mapSize.Y; y += tileSize {
+		line := image.Rect(0, y, mapSize.X, y+1).Sub(g.Camera)
+		drawDotted(screen, line, ColorDark)
+	}
+
+	tile := image.Pt(g.Cursor.Coords.X/tileSize, (g.Cursor.Coords.Y-hudOffset)/tileSize)
+	corner := image.Pt(tile.X*tileSize, tile.Y*tileSize+hudOffset)
+	outline := image.Rectangle{corner, corner.Add(image.Pt(tileSize+1, tileSize+1))}
+	drawOutline(screen, outline.Sub(g.Camera), ColorDark)
+}
+
+// Draw lines through the middle of each waypoint of every path, showing the
+// way creeps will go, shifted by an offset so it lines up with the map
+func drawRoutes(screen *ebiten.Image, paths []Ways, offset image.Point) {
+	for _, path := range paths {
+		for i := 1; i < len(path); i++ {
+			from := WaypointCoords(path[i-1]).Sub(offset)
+			to := WaypointCoords(path[i]).Sub(offset)
+			ebitenutil.DrawLine(screen,
+				float64(from.X),
+				float64(from.Y),
+				float64(to.X),
+				float64(to.Y),
+				ColorDark,
+			)
+		}
+	}
+}
+
+// Ordered dithering thresholds, so that a colour can cover the screen a bit
+// at a time on a 1-bit screen
+var bayer4 = [4][4]int{
+	{0, 8, 2, 10},
+	{12, 4, 14, 6},
+	{3, 11, 1, 9},
+	{15, 7, 13, 5},
+}
+
+// Cover part of a rectangle in a colour with an even dither pattern, from none
+// of it at 0 to all of it at 1
+func drawDithered(screen *ebiten.Image, r image.Rectangle, clr color.Color, amount float64) {
+	level := int(amount * 16)
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			if bayer4[y%4][x%4] < level {
+				screen.Set(x, y, clr)
+			}
+		}
+	}
+}
+
+// How many ticks the screen takes to fade out after winning a map, which is
+// as long as the wait before the next one
+const WinFadeTicks = 2 * 60
+
+// Fade the map out to the light colour while gloating about a win, leaving
+// the HUD showing the grade
+func (g *Game) drawWinFade(screen *ebiten.Image) {
+	if g.WinFade <= 0 {
+		return
+	}
+	drawDithered(screen, image.Rect(0, hudHeight, g.Size.X, g.Size.Y), ColorLight, winFadeAmount(g.WinFade))
+}
+
+// How far the screen has faded out after fading for some ticks, from 0 for
+// not at all to 1 for completely
+func winFadeAmount(ticks int) float64 {
+	return max(0, min(1, float64(ticks)/WinFadeTicks))
+}
+
+// Hatch the tiles you can't build on so you can see them before trying
+func (g *Game) drawNoBuild(screen *ebiten.Image) {
+	if g.State != gameStateBuild {
+		return
+	}
+	for _, v := range g.NoBuild {
+		drawHatched(screen, noBuildRect(v).Sub(g.Camera), ColorDark)
+	}
+}
+
+// Blink an outline around the base tiles after a creep reaches one
+func (g *Game) drawBaseFlash(screen *ebiten.Image) {
+	if g.BaseFlash <= 0 || (g.BaseFlash/5)%2 == 0 {
+		return
+	}
+	tileSize := 7
+	hudOffset := 5
+	for _, path := range g.Paths {
+		base := path[len(path)-1]
+		corner := image.Pt(base.X*tileSize, base.Y*tileSize+hudOffset)
+		outline := image.Rectangle{corner, corner.Add(image.Pt(tileSize+1, tileSize+1))}
+		drawOutline(screen, outline.Sub(g.Camera), ColorDark)
+	}
+}
+
+// How many ticks before a creep is sent that its spawn tile starts blinking
+const SpawnTelegraphTicks = 45
+
+// Blink an outline around the tile the next creep comes from when it's about
+// to be sent
+func (g *Game) drawSpawnTelegraph(screen *ebiten.Image) {
+	next := g.NextSpawnIn()
+	if next <= 0 || next > SpawnTelegraphTicks {
+		return
+	}
+	if g.Spawned >= len(g.Waves[g.MapIndex]) || (next/5)%2 == 0 {
+		return
+	}
+	tileSize := 7
+	hudOffset := 5
+	path := g.Paths[g.nextPath()]
+	if len(path) < 2 {
+		return
+	}
+	spawn := path[0]
+	corner := image.Pt(spawn.X*tileSize, spawn.Y*tileSize+hudOffset)
+	outline := image.Rectangle{corner, corner.Add(image.Pt(tileSize+1, tileSize+1))}
+	drawOutline(screen, outline.Sub(g.Camera), ColorDark)
+}
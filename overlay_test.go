@@ -0,0 +1,44 @@
+// Copyright 2022 Siôn le Roux.  All rights reserved.
+// Use of this source code is subject to an MIT-style
+// licence which can be found in the LICENSE file.
+
+package main
+
+import "testing"
+
+func TestWinFade(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	g := newTestGame()
+	g.State = gameStateWin
+	g.Update()
+	if g.State != gameStateWaiting || g.WinFade != 1 {
+		t.Fatalf("state %d fading for %d ticks after winning, want waiting %d and just started fading",
+			g.State, g.WinFade, gameStateWaiting)
+	}
+
+	last := winFadeAmount(g.WinFade)
+	for i := 0; i < WinFadeTicks/2 && g.State == gameStateWaiting; i++ {
+		g.Update()
+		amount := winFadeAmount(g.WinFade)
+		if amount <= last {
+			t.Fatalf("fade went from %v to %v after %d updates, want it to keep going", last, amount, i+1)
+		}
+		last = amount
+	}
+
+	tests := []struct {
+		ticks int
+		want  float64
+	}{
+		{0, 0},
+		{WinFadeTicks / 4, 0.25},
+		{WinFadeTicks / 2, 0.5},
+		{WinFadeTicks, 1},
+		{WinFadeTicks * 2, 1},
+	}
+	for _, tt := range tests {
+		if got := winFadeAmount(tt.ticks); got != tt.want {
+			t.Errorf("winFadeAmount(%d) = %v, want %v", tt.ticks, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+// Copyright 2022 Siôn le Roux.  All rights reserved.
+// Use of this source code is subject to an MIT-style
+// licence which can be found in the LICENSE file.
+
+package main
+
+import (
+	"image"
+	"image/color"
+	"sync"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// PalettePreset is a named pair of screen colours the game can be shown in
+type PalettePreset struct {
+	Name        string
+	Light       color.Color
+	Dark        color.Color
+	Transparent color.Color // Used where images have no colour
+}
+
+// The palettes you can choose from in the options, the first one is the
+// original Nokia greens
+var palettePresets = []PalettePreset{
+	{"NOKIA", color.RGBA{199, 240, 216, 255}, color.RGBA{67, 82, 61, 255}, color.RGBA{}},
+	{"MONO", color.RGBA{255, 255, 255, 255}, color.RGBA{0, 0, 0, 255}, color.RGBA{}},
+	{"AMBER", color.RGBA{255, 191, 0, 255}, color.RGBA{51, 26, 0, 255}, color.RGBA{}},
+}
+
+// Find the palette preset with a name, falling back to the first one
+func palettePreset(name string) int {
+	for i, p := range palettePresets {
+		if p.Name == name {
+			return i
+		}
+	}
+	return 0
+}
+
+// SetPalette changes the screen colours the game is drawn in
+func (g *Game) SetPalette(index int) {
+	p := palettePresets[index]
+	ColorLight = p.Light
+	ColorDark = p.Dark
+	ColorTransparent = p.Transparent
+	NokiaPalette = color.Palette{ColorTransparent, ColorDark, ColorLight}
+	g.Settings.Palette = p.Name
+	recolorThemedImages(NokiaPalette)
+	if g.Cursor != nil {
+		g.Cursor.Recolor()
+	}
+}
+
+// The colours the image assets are drawn in
+var sourcePalette = color.Palette{
+	ColorTransparent,
+	palettePresets[0].Dark,
+	palettePresets[0].Light,
+}
+
+// A loaded image with the pixels it was loaded with, so that it can be
+// recoloured again each time the palette changes
+type themedImage struct {
+	source image.Image
+	image  *ebiten.Image
+}
+
+// Every image loaded from the assets, which can be added to while loading
+var (
+	themedImages      []themedImage
+	themedImagesMutex sync.Mutex
+)
+
+// Keep a loaded image so that it's recoloured when the palette changes, and
+// colour it in the current palette straight away
+func registerThemedImage(source image.Image, img *ebiten.Image) {
+	themedImagesMutex.Lock()
+	defer themedImagesMutex.Unlock()
+	themedImages = append(themedImages, themedImage{source, img})
+	img.WritePixels(RecolorImage(source, sourcePalette, NokiaPalette).Pix)
+}
+
+// Recolour every image loaded from the assets into a palette
+func recolorThemedImages(to color.Palette) {
+	themedImagesMutex.Lock()
+	defer themedImagesMutex.Unlock()
+	for _, t := range themedImages {
+		t.image.WritePixels(RecolorImage(t.source, sourcePalette, to).Pix)
+	}
+}
+
+// RecolorImage makes a copy of an image with each colour from one palette
+// swapped for the colour at the same place in another, using the closest
+// colour for anything not in the palette and leaving transparency alone
+func RecolorImage(img image.Image, from, to color.Palette) *image.RGBA {
+	b := img.Bounds()
+	out := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			c := img.At(x, y)
+			if _, _, _, a := c.RGBA(); a == 0 {
+				continue
+			}
+			if i := from.Index(c); i < len(to) {
+				c = to[i]
+			}
+			out.Set(x-b.Min.X, y-b.Min.Y, c)
+		}
+	}
+	return out
+}
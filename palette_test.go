@@ -0,0 +1,35 @@
+// Copyright 2022 Siôn le Roux.  All rights reserved.
+// Use of this source code is subject to an MIT-style
+// licence which can be found in the LICENSE file.
+
+package main
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestRecolorImage(t *testing.T) {
+	light := color.RGBA{0xc7, 0xf0, 0xd8, 0xff}
+	dark := color.RGBA{0x43, 0x52, 0x3d, 0xff}
+	newLight := color.RGBA{0xff, 0xff, 0xff, 0xff}
+	newDark := color.RGBA{0x00, 0x00, 0x00, 0xff}
+
+	img := image.NewRGBA(image.Rect(10, 20, 14, 21)) // Not at the origin
+	img.Set(10, 20, light)
+	img.Set(11, 20, dark)
+	img.Set(12, 20, color.RGBA{0x50, 0x60, 0x40, 0xff}) // Closest to dark
+	// The last pixel is left transparent
+
+	out := RecolorImage(img, color.Palette{light, dark}, color.Palette{newLight, newDark})
+	want := []color.RGBA{newLight, newDark, newDark, {}}
+	if out.Bounds() != image.Rect(0, 0, 4, 1) {
+		t.Fatalf("recoloured image has bounds %v, want %v", out.Bounds(), image.Rect(0, 0, 4, 1))
+	}
+	for x, w := range want {
+		if got := out.RGBAAt(x, 0); got != w {
+			t.Errorf("pixel %d recoloured to %v, want %v", x, got, w)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+// Copyright 2022 Siôn le Roux.  All rights reserved.
+// Use of this source code is subject to an MIT-style
+// licence which can be found in the LICENSE file.
+
+package main
+
+import (
+	"image"
+	"log"
+	"slices"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// ToggleQueued adds the tile under the cursor to the build queue, or takes it
+// off again if it's already queued
+func (g *Game) ToggleQueued() {
+	coords := g.Cursor.Coords
+	if k := slices.Index(g.BuildQueue, coords); k != -1 {
+		g.BuildQueue = slices.Delete(g.BuildQueue, k, k+1)
+		return
+	}
+	g.BuildQueue = append(g.BuildQueue, coords)
+	g.ShowNotice(g.T("QUEUED"))
+}
+
+// Build the next queued tower once there's enough money for it, dropping it
+// from the queue if it can't be built there at all
+func (g *Game) updateBuildQueue() {
+	if len(g.BuildQueue) == 0 {
+		return
+	}
+	result := buyTowerAt(g, g.BuildQueue[0])
+	if result == buyRejectedFunds {
+		return
+	}
+	if result.Rejected() {
+		log.Println("Dropping queued tower:", result)
+	}
+	g.BuildQueue = g.BuildQueue[1:]
+}
+
+// Dot in the tiles waiting to be built on, like a faint ghost of the tower
+func (g *Game) drawBuildQueue(screen *ebiten.Image) {
+	tileSize := 7
+	hudOffset := 5
+	for _, coords := range g.BuildQueue {
+		corner := image.Pt(
+			coords.X/tileSize*tileSize,
+			(coords.Y-hudOffset)/tileSize*tileSize+hudOffset,
+		)
+		tile := image.Rectangle{corner, corner.Add(image.Pt(tileSize, tileSize))}
+		drawDotted(screen, tile.Sub(g.Camera), ColorDark)
+	}
+}
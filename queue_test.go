@@ -0,0 +1,53 @@
+// Copyright 2022 Siôn le Roux.  All rights reserved.
+// Use of this source code is subject to an MIT-style
+// licence which can be found in the LICENSE file.
+
+package main
+
+import "testing"
+
+func TestBuildQueueWaitsForMoney(t *testing.T) {
+	g := newTestGame()
+	g.Money = 50
+	for _, x := range []int{1, 2} {
+		g.Cursor.Coords = tileCoords(x, 2)
+		g.ToggleQueued()
+	}
+
+	g.updateBuildQueue()
+	if len(g.Towers) != 0 || len(g.BuildQueue) != 2 {
+		t.Fatalf("with %d money built %d towers leaving %d queued, want 0 and 2",
+			g.Money, len(g.Towers), len(g.BuildQueue))
+	}
+
+	// Money comes in bit by bit until there's enough for each tower
+	built := 0
+	for i := 0; i < 100 && len(g.BuildQueue) > 0; i++ {
+		g.earn(10)
+		g.updateBuildQueue()
+		if len(g.Towers) > built {
+			built = len(g.Towers)
+			if g.Money >= 10 {
+				t.Errorf("built tower %d with %d money left over, want it built as soon as it could be", built, g.Money)
+			}
+		}
+	}
+	if len(g.Towers) != 2 {
+		t.Fatalf("built %d queued towers, want 2", len(g.Towers))
+	}
+	for i, want := range []int{1, 2} {
+		if g.Towers[i].Coords != tileCoords(want, 2) {
+			t.Errorf("tower %d built at %v, want %v", i, g.Towers[i].Coords, tileCoords(want, 2))
+		}
+	}
+}
+
+func TestBuildQueueDropsUnbuildable(t *testing.T) {
+	g := newTestGame()
+	g.Cursor.Coords = tileCoords(4, 3) // No-build tile
+	g.ToggleQueued()
+	g.updateBuildQueue()
+	if len(g.BuildQueue) != 0 || len(g.Towers) != 0 {
+		t.Errorf("queued no-build tile left %d queued and %d towers, want none", len(g.BuildQueue), len(g.Towers))
+	}
+}
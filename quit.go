@@ -0,0 +1,54 @@
+// Copyright 2022 Siôn le Roux.  All rights reserved.
+// Use of this source code is subject to an MIT-style
+// licence which can be found in the LICENSE file.
+
+package main
+
+import (
+	"log"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/hajimehoshi/ebiten/v2/text"
+	"golang.org/x/image/font"
+)
+
+// Save the game if one is being played, so it can be continued next time
+func (g *Game) saveInProgress() {
+	if g.State != gameStateBuild && g.State != gameStatePause {
+		return
+	}
+	if err := g.Save(); err != nil {
+		log.Println("Saving failed:", err)
+	}
+}
+
+// Ask whether to quit instead of quitting straight away, remembering what
+// was going on so it can carry on if you don't
+func (g *Game) askQuit() {
+	g.QuitFrom = g.State
+	g.State = gameStateQuit
+}
+
+// Handle input on the quit prompt, Y saves the game and quits, N or Escape
+// goes back to what you were doing
+func (g *Game) updateQuit() error {
+	if inpututil.IsKeyJustPressed(ebiten.KeyY) {
+		g.State = g.QuitFrom
+		g.saveInProgress()
+		return ebiten.Termination
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyN) || inpututil.IsKeyJustPressed(ebiten.KeyEscape) {
+		g.State = g.QuitFrom
+	}
+	return nil
+}
+
+// Draw the quit prompt in the middle of the screen
+func (g *Game) drawQuit(screen *ebiten.Image) {
+	txt := g.T("QUIT? Y/N")
+	txtf, _ := font.BoundString(g.TextFont, txt)
+	txth := (txtf.Max.Y - txtf.Min.Y).Ceil() / 2
+	txtw := (txtf.Max.X - txtf.Min.X).Ceil() / 2
+	text.Draw(screen, txt, g.TextFont, g.Size.X/2-txtw, g.Size.Y/2-txth, ColorDark)
+}
@@ -0,0 +1,225 @@
+// Copyright 2022 Siôn le Roux.  All rights reserved.
+// Use of this source code is subject to an MIT-style
+// licence which can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"image"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+// Version of the save file format, saves with any other version are ignored
+const saveVersion = 3
+
+// How often the game is saved while you're playing, in ticks
+const AutoSaveInterval = 10 * 60
+
+// GameSnapshot is the state of a game in progress, enough to carry on playing
+// where you left off, leaving out anything that can be loaded from assets
+type GameSnapshot struct {
+	Version       int          `json:"version"`
+	MapIndex      int          `json:"map"`
+	Difficulty    Difficulty   `json:"difficulty"`
+	Seed          uint64       `json:"seed"` // How the waves were shuffled
+	Money         int          `json:"money"`
+	Lives         int          `json:"lives"`
+	BaseWear      int          `json:"base_wear"`
+	Tick          int          `json:"tick"`
+	Kills         int          `json:"kills"`
+	Leaks         int          `json:"leaks"`
+	Earned        int          `json:"earned"`
+	Spent         int          `json:"spent"`
+	Spawned       int          `json:"spawned"`        // How many creeps of the wave were sent
+	SpawnCooldown int          `json:"spawn_cooldown"` // Ticks until the next one is sent
+	WaveCountdown int          `json:"wave_countdown"` // Ticks until the wave starts
+	Towers        Towers       `json:"towers"`
+	Creeps        []SavedCreep `json:"creeps"`
+}
+
+// SavedCreep is a creep that was on its way to the base when the game was
+// saved, identified by where it comes in the wave
+type SavedCreep struct {
+	Index        int `json:"index"`
+	Path         int `json:"path"`
+	X            int `json:"x"`
+	Y            int `json:"y"`
+	NextWaypoint int `json:"next_waypoint"`
+	Health       int `json:"health"`
+	Shield       int `json:"shield"`
+}
+
+// NewGameSnapshot captures the state of the game in progress
+func NewGameSnapshot(g *Game) *GameSnapshot {
+	s := &GameSnapshot{
+		Version:       saveVersion,
+		MapIndex:      g.MapIndex,
+		Difficulty:    g.Difficulty,
+		Seed:          g.Seed,
+		Money:         g.Money,
+		Lives:         g.Lives,
+		BaseWear:      g.BaseWear,
+		Tick:          g.Tick,
+		Kills:         g.Kills,
+		Leaks:         g.Leaks,
+		Earned:        g.TotalEarned,
+		Spent:         g.TotalSpent,
+		Spawned:       g.Spawned,
+		SpawnCooldown: g.SpawnCooldown,
+		WaveCountdown: g.WaveCountdown,
+		Towers:        g.Towers,
+	}
+	wave := g.Waves[g.MapIndex]
+	for _, c := range g.Creeps {
+		if c.Dummy {
+			continue // Only there for testing
+		}
+		s.Creeps = append(s.Creeps, SavedCreep{
+			Index:        slices.Index(wave, c),
+			Path:         c.PathIndex,
+			X:            c.Coords.X,
+			Y:            c.Coords.Y,
+			NextWaypoint: c.NextWaypoint,
+			Health:       c.Health,
+			Shield:       c.Shield,
+		})
+	}
+	return s
+}
+
+// Save writes the game in progress to the save file
+func (g *Game) Save() error {
+	name, err := configPath("save.json")
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(NewGameSnapshot(g))
+	if err != nil {
+		return fmt.Errorf("encoding save: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		return fmt.Errorf("creating save directory: %w", err)
+	}
+	if err := os.WriteFile(name, data, 0644); err != nil {
+		return fmt.Errorf("writing save %s: %w", name, err)
+	}
+	return nil
+}
+
+// LoadSave reads the save file, returning an error if there isn't one or if
+// it was saved by an incompatible version of the game
+func LoadSave() (*GameSnapshot, error) {
+	name, err := configPath("save.json")
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, fmt.Errorf("reading save %s: %w", name, err)
+	}
+	var s GameSnapshot
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("decoding save %s: %w", name, err)
+	}
+	if s.Version != saveVersion {
+		return nil, fmt.Errorf("save %s has version %d, want %d", name, s.Version, saveVersion)
+	}
+	return &s, nil
+}
+
+// DeleteSave removes the save file once there's nothing left to continue
+func DeleteSave() {
+	name, err := configPath("save.json")
+	if err != nil {
+		return
+	}
+	os.Remove(name)
+}
+
+// Restore carries on a saved game, rebuilding its towers and creeps
+func (g *Game) Restore(s *GameSnapshot) error {
+	maps := []MapData{g.MapData1, g.MapData2}
+	if s.MapIndex < 0 || s.MapIndex >= len(maps) {
+		return fmt.Errorf("save has unknown map %d", s.MapIndex)
+	}
+	if s.Lives <= 0 {
+		return fmt.Errorf("save has no lives left")
+	}
+	// Shuffle the waves the way they were when saved, but leave the game as
+	// it was in case the save turns out to be broken
+	seed := g.Seed
+	g.Seed = s.Seed
+	waves := NewWaves(g)
+	g.Seed = seed
+	wave := waves[s.MapIndex]
+	if s.Spawned < 0 || s.Spawned > len(wave) {
+		return fmt.Errorf("save has sent %d of %d creeps", s.Spawned, len(wave))
+	}
+	paths := maps[s.MapIndex].AllPaths()
+
+	var creeps Creeps
+	seen := map[int]bool{}
+	for _, sc := range s.Creeps {
+		if sc.Index < 0 || sc.Index >= s.Spawned || sc.Path < 0 || sc.Path >= len(paths) ||
+			sc.NextWaypoint < 1 || sc.NextWaypoint >= len(paths[sc.Path]) {
+			return fmt.Errorf("save has invalid creep %d", sc.Index)
+		}
+		if seen[sc.Index] {
+			return fmt.Errorf("save has creep %d more than once", sc.Index)
+		}
+		seen[sc.Index] = true
+		c := wave[sc.Index]
+		c.PathIndex = sc.Path
+		c.Coords = image.Pt(sc.X, sc.Y)
+		c.NextWaypoint = sc.NextWaypoint
+		c.Health = sc.Health
+		c.Shield = sc.Shield
+		creeps = append(creeps, c)
+	}
+
+	var towers Towers
+	for _, t := range s.Towers {
+		towers = append(towers, t.Rehydrate(g))
+	}
+
+	g.MapIndex = s.MapIndex
+	g.Difficulty = s.Difficulty
+	g.Seed = s.Seed
+	g.Paths = paths
+	g.NoBuild = maps[s.MapIndex].NoBuild
+	g.Waves = waves
+	g.Creeps = creeps
+	g.Towers = towers
+	g.Placements = nil
+	g.BuildQueue = nil
+	g.TowerMenu = false
+	g.Money = s.Money
+	g.Lives = s.Lives
+	g.BaseWear = s.BaseWear
+	g.Effects = nil
+	g.Coins = nil
+	g.Tick = s.Tick
+	g.Kills = s.Kills
+	g.Leaks = s.Leaks
+	g.TotalEarned = s.Earned
+	g.TotalSpent = s.Spent
+	g.Spawned = s.Spawned
+	g.SpawnCooldown = s.SpawnCooldown
+	g.WaveCountdown = s.WaveCountdown
+	return nil
+}
+
+// Save the game every so often so that progress isn't lost
+func (g *Game) autoSave() {
+	if g.Sandbox || g.State == gameStateDemo || g.Tick%AutoSaveInterval != 0 {
+		return
+	}
+	if err := g.Save(); err != nil {
+		log.Println("Auto-save failed:", err)
+	}
+}
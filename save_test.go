@@ -0,0 +1,136 @@
+// Copyright 2022 Siôn le Roux.  All rights reserved.
+// Use of this source code is subject to an MIT-style
+// licence which can be found in the LICENSE file.
+
+package main
+
+import (
+	"image"
+	"testing"
+)
+
+func TestSaveRoundTrip(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	g := newTestGame()
+
+	basic := NewBasicTower(g)
+	basic.Coords = image.Pt(14, 26)
+	basic.Levels = [trackCount]int{1, 0, 2}
+	basic.TargetMode = targetModeClosest
+	basic.Facing = 3
+	basic.Invested = 450
+	strong := NewStrongTower(g)
+	strong.Coords = image.Pt(35, 33)
+	strong.Invested = 600
+	bank := NewBankTower(g)
+	bank.Coords = image.Pt(7, 40)
+	bank.Earned = 75
+	g.Towers = Towers{basic, strong, bank}
+
+	g.WaveCountdown = 0
+	for i := 0; i < 1000 && len(g.Creeps) < 2; i++ {
+		g.spawnCreeps()
+	}
+	if len(g.Creeps) < 2 {
+		t.Fatalf("spawned %d creeps, want 2", len(g.Creeps))
+	}
+	g.Creeps[0].Coords = image.Pt(40, 12)
+	g.Creeps[0].NextWaypoint = 1
+	g.Creeps[0].Health = 7
+	g.Creeps[1].Coords = image.Pt(59, 20)
+	g.Creeps[1].NextWaypoint = 2
+	g.Money = 321
+	g.Lives = 4
+	g.Tick = 999
+	g.Kills = 5
+
+	if err := g.Save(); err != nil {
+		t.Fatal(err)
+	}
+	s, err := LoadSave()
+	if err != nil {
+		t.Fatal(err)
+	}
+	restored := newTestGame()
+	if err := restored.Restore(s); err != nil {
+		t.Fatal(err)
+	}
+
+	if restored.Money != g.Money || restored.Lives != g.Lives ||
+		restored.Tick != g.Tick || restored.Kills != g.Kills ||
+		restored.Spawned != g.Spawned {
+		t.Errorf("restored money %d, lives %d, tick %d, kills %d, spawned %d, want %d, %d, %d, %d, %d",
+			restored.Money, restored.Lives, restored.Tick, restored.Kills, restored.Spawned,
+			g.Money, g.Lives, g.Tick, g.Kills, g.Spawned)
+	}
+
+	if len(restored.Towers) != len(g.Towers) {
+		t.Fatalf("restored %d towers, want %d", len(restored.Towers), len(g.Towers))
+	}
+	for i, want := range g.Towers {
+		got := restored.Towers[i]
+		if got.Kind != want.Kind || got.Coords != want.Coords || got.Levels != want.Levels ||
+			got.TargetMode != want.TargetMode || got.Facing != want.Facing ||
+			got.Invested != want.Invested || got.Earned != want.Earned {
+			t.Errorf("tower %d restored as %v at %v, want %v at %v with the same settings",
+				i, got.Kind, got.Coords, want.Kind, want.Coords)
+		}
+	}
+
+	if len(restored.Creeps) != len(g.Creeps) {
+		t.Fatalf("restored %d creeps, want %d", len(restored.Creeps), len(g.Creeps))
+	}
+	for i, want := range g.Creeps {
+		got := restored.Creeps[i]
+		if got.Coords != want.Coords || got.NextWaypoint != want.NextWaypoint ||
+			got.Health != want.Health || got.PathIndex != want.PathIndex {
+			t.Errorf("creep %d restored at %v heading for %d with %d health, want %v heading for %d with %d health",
+				i, got.Coords, got.NextWaypoint, got.Health, want.Coords, want.NextWaypoint, want.Health)
+		}
+	}
+}
+
+func TestRestoreCorruptSave(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	g := newTestGame()
+	g.WaveCountdown = 0
+	for i := 0; i < 1000 && len(g.Creeps) < 2; i++ {
+		g.spawnCreeps()
+	}
+	if len(g.Creeps) < 2 {
+		t.Fatalf("spawned %d creeps, want 2", len(g.Creeps))
+	}
+	if err := g.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		corrupt func(s *GameSnapshot)
+	}{
+		{"creep before the first waypoint", func(s *GameSnapshot) { s.Creeps[0].NextWaypoint = -1 }},
+		{"creep heading for the spawn point", func(s *GameSnapshot) { s.Creeps[0].NextWaypoint = 0 }},
+		{"creep past the last waypoint", func(s *GameSnapshot) { s.Creeps[0].NextWaypoint = 99 }},
+		{"same creep twice", func(s *GameSnapshot) { s.Creeps[1].Index = s.Creeps[0].Index }},
+		{"creep not sent yet", func(s *GameSnapshot) { s.Creeps[0].Index = s.Spawned }},
+		{"unknown map", func(s *GameSnapshot) { s.MapIndex = 7 }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := LoadSave()
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.corrupt(s)
+			restored := newTestGame()
+			money := restored.Money
+			if err := restored.Restore(s); err == nil {
+				t.Fatal("restoring a corrupt save succeeded, want an error")
+			}
+			if len(restored.Creeps) != 0 || restored.Money != money {
+				t.Errorf("corrupt save left %d creeps and %d money, want the game untouched",
+					len(restored.Creeps), restored.Money)
+			}
+		})
+	}
+}
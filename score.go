@@ -0,0 +1,73 @@
+// Copyright 2022 Siôn le Roux.  All rights reserved.
+// Use of this source code is subject to an MIT-style
+// licence which can be found in the LICENSE file.
+
+package main
+
+import "log"
+
+// Thresholds for the grade you get for clearing a map
+const (
+	GradeSCostPerKill = 60      // Most money in towers per kill for an S
+	GradeACostPerKill = 100     // Most money in towers per kill for an A
+	GradeClearTicks   = 20 * 60 // Most ticks after the wave's last creep is due for an S
+)
+
+// RoundStats sum up how well you did on a map
+type RoundStats struct {
+	Kills     int // Creeps killed
+	Leaks     int // Creeps that reached the base
+	Spent     int // Money in the towers you built
+	Ticks     int // How long it took to clear the map
+	WaveTicks int // How long it takes to send every creep without skipping
+	Earned    int // Money earned from kills, bonuses and selling
+	Paid      int // Money paid for towers and upgrades, including ones sold since
+}
+
+// NewRoundStats sums up the round that was just played
+func NewRoundStats(g *Game) RoundStats {
+	stats := RoundStats{
+		Kills:  g.Kills,
+		Leaks:  g.Leaks,
+		Ticks:  g.Tick,
+		Earned: g.TotalEarned,
+		Paid:   g.TotalSpent,
+	}
+	for _, t := range g.Towers {
+		stats.Spent += t.Invested
+	}
+	for _, c := range g.Waves[g.MapIndex] {
+		stats.WaveTicks += c.SpawnDelay
+	}
+	return stats
+}
+
+// Add money earned during the round
+func (g *Game) earn(amount int) {
+	g.Money += amount
+	g.TotalEarned += amount
+}
+
+// Write how much money came in and went out during the round to the log, for
+// balancing the economy
+func (g *Game) logMoney() {
+	log.Printf("Earned %d and spent %d this round\n", g.TotalEarned, g.TotalSpent)
+}
+
+// Work out the grade for a round, S is for a quick, cheap clear with nothing
+// getting through and C is for scraping by
+func computeGrade(stats RoundStats) rune {
+	if stats.Leaks > 0 {
+		return 'C'
+	}
+	costPerKill := stats.Spent / max(1, stats.Kills)
+	fast := stats.Ticks <= stats.WaveTicks+GradeClearTicks
+	switch {
+	case costPerKill <= GradeSCostPerKill && fast:
+		return 'S'
+	case costPerKill <= GradeACostPerKill:
+		return 'A'
+	default:
+		return 'B'
+	}
+}
@@ -0,0 +1,61 @@
+// Copyright 2022 Siôn le Roux.  All rights reserved.
+// Use of this source code is subject to an MIT-style
+// licence which can be found in the LICENSE file.
+
+package main
+
+import "testing"
+
+func TestComputeGrade(t *testing.T) {
+	const kills, wave = 10, 1000
+	tests := []struct {
+		name  string
+		stats RoundStats
+		want  rune
+	}{
+		{"cheap and fast", RoundStats{Kills: kills, Spent: GradeSCostPerKill * kills, Ticks: wave + GradeClearTicks, WaveTicks: wave}, 'S'},
+		{"cheap but slow", RoundStats{Kills: kills, Spent: GradeSCostPerKill * kills, Ticks: wave + GradeClearTicks + 1, WaveTicks: wave}, 'A'},
+		{"just too dear for an S", RoundStats{Kills: kills, Spent: (GradeSCostPerKill + 1) * kills, Ticks: wave, WaveTicks: wave}, 'A'},
+		{"as dear as an A gets", RoundStats{Kills: kills, Spent: GradeACostPerKill * kills, Ticks: wave, WaveTicks: wave}, 'A'},
+		{"just too dear for an A", RoundStats{Kills: kills, Spent: (GradeACostPerKill + 1) * kills, Ticks: wave, WaveTicks: wave}, 'B'},
+		{"one leak", RoundStats{Kills: kills, Leaks: 1, Ticks: wave, WaveTicks: wave}, 'C'},
+		{"no kills", RoundStats{Spent: GradeSCostPerKill, Ticks: wave, WaveTicks: wave}, 'S'},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := computeGrade(tt.stats); got != tt.want {
+				t.Errorf("computeGrade(%+v) = %c, want %c", tt.stats, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEarnAndSpendTotals(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	g := newTestGame()
+
+	kill := NewSmallCreep(g)
+	kill.Health = 0
+	kill.Update(g)
+	if g.TotalEarned != kill.Loot || g.TotalSpent != 0 {
+		t.Errorf("after a kill earned %d and spent %d, want %d and 0", g.TotalEarned, g.TotalSpent, kill.Loot)
+	}
+
+	g.Cursor.Coords = tileCoords(2, 2)
+	BuyTower(g)
+	cost := g.Towers[0].Cost
+	if g.TotalEarned != kill.Loot || g.TotalSpent != cost {
+		t.Errorf("after building earned %d and spent %d, want %d and %d", g.TotalEarned, g.TotalSpent, kill.Loot, cost)
+	}
+
+	sale := g.Towers[0].SellValue()
+	g.sellTower(0)
+	if g.TotalEarned != kill.Loot+sale || g.TotalSpent != cost {
+		t.Errorf("after selling earned %d and spent %d, want %d and %d", g.TotalEarned, g.TotalSpent, kill.Loot+sale, cost)
+	}
+
+	g.Reset(false)
+	if g.TotalEarned != 0 || g.TotalSpent != 0 {
+		t.Errorf("after resetting earned %d and spent %d, want 0 and 0", g.TotalEarned, g.TotalSpent)
+	}
+}
@@ -0,0 +1,72 @@
+// Copyright 2022 Siôn le Roux.  All rights reserved.
+// Use of this source code is subject to an MIT-style
+// licence which can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Settings are the options you chose, kept between runs of the game
+type Settings struct {
+	Palette      string `json:"palette"`       // Name of the palette preset
+	NoGloat      bool   `json:"no_gloat"`      // Skip the wait after winning or losing
+	WindowScale  int    `json:"window_scale"`  // How many times bigger than the game the window is
+	HUDStyle     string `json:"hud_style"`     // Name of the way money and lives are shown
+	LastStand    bool   `json:"last_stand"`    // Creeps that reach the base hurt it less the more hurt they are
+	Shuffle      bool   `json:"shuffle"`       // Send the creeps in each wave in a different order every time
+	Spectate     bool   `json:"spectate"`      // Watch the creeps overrun the base after losing
+	TextSize     string `json:"text_size"`     // Name of the size messages and menus are drawn at
+	Language     string `json:"language"`      // Code of the language on-screen text is shown in
+	TutorialDone bool   `json:"tutorial_done"` // Whether the hints for playing the first time were all followed
+}
+
+// Where a file the game keeps between runs is stored
+func configPath(name string) (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("finding config directory: %w", err)
+	}
+	return filepath.Join(dir, "nokia-defence", name), nil
+}
+
+// LoadSettings reads the settings file, returning the default settings and an
+// error if it can't be read
+func LoadSettings() (Settings, error) {
+	var s Settings
+	name, err := configPath("settings.json")
+	if err != nil {
+		return s, err
+	}
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return s, fmt.Errorf("reading settings %s: %w", name, err)
+	}
+	if err := json.Unmarshal(data, &s); err != nil {
+		return Settings{}, fmt.Errorf("decoding settings %s: %w", name, err)
+	}
+	return s, nil
+}
+
+// Save writes the settings to the settings file
+func (s Settings) Save() error {
+	name, err := configPath("settings.json")
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(s)
+	if err != nil {
+		return fmt.Errorf("encoding settings: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		return fmt.Errorf("creating config directory: %w", err)
+	}
+	if err := os.WriteFile(name, data, 0644); err != nil {
+		return fmt.Errorf("writing settings %s: %w", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,24 @@
+// Copyright 2022 Siôn le Roux.  All rights reserved.
+// Use of this source code is subject to an MIT-style
+// licence which can be found in the LICENSE file.
+
+package main
+
+// Let the creeps that are left carry on to the base after you've lost, with
+// the towers standing still, until the game resets
+func (g *Game) updateOverrun(steps int) {
+	for i := 0; i < steps; i++ {
+		var left Creeps
+		for _, c := range g.Creeps {
+			if err := c.Update(g); err == nil {
+				left = append(left, c)
+			}
+		}
+		g.Creeps = left
+		g.updateEffects()
+		g.updateCoins()
+		if g.BaseFlash > 0 {
+			g.BaseFlash--
+		}
+	}
+}
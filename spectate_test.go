@@ -0,0 +1,29 @@
+// Copyright 2022 Siôn le Roux.  All rights reserved.
+// Use of this source code is subject to an MIT-style
+// licence which can be found in the LICENSE file.
+
+package main
+
+import "testing"
+
+func TestOverrunFreezesTowers(t *testing.T) {
+	g := newTestGame()
+	g.State = gameStateOverrun
+	c := NewSmallCreep(g)
+	c.Coords = WaypointCoords(testMapData.Ways[0])
+	g.Creeps = Creeps{c}
+	tower := NewBasicTower(g)
+	tower.Coords = c.Coords
+	tower.Target = c
+	g.Towers = Towers{tower}
+
+	start := c.Coords
+	g.updateOverrun(30)
+	if c.Coords == start {
+		t.Errorf("creep still at %v after overrunning for 30 ticks, want it to keep moving", start)
+	}
+	if c.Health != c.MaxHealth || tower.Dealt != 0 {
+		t.Errorf("tower dealt %d damage leaving the creep on %d/%d health, want the towers stopped",
+			tower.Dealt, c.Health, c.MaxHealth)
+	}
+}
@@ -0,0 +1,70 @@
+// Copyright 2022 Siôn le Roux.  All rights reserved.
+// Use of this source code is subject to an MIT-style
+// licence which can be found in the LICENSE file.
+
+package main
+
+import (
+	"golang.org/x/image/font"
+)
+
+// TextSize is how big messages and menus are drawn, which is easier to read
+// at bigger window scales, the HUD bar is too small for anything but the
+// smallest so it doesn't change
+type TextSize int
+
+const (
+	textSizeSmall TextSize = iota
+	textSizeLarge
+	textSizeCount
+)
+
+// String is the name of the size shown in the options and kept in settings
+func (s TextSize) String() string {
+	switch s {
+	case textSizeLarge:
+		return "LARGE"
+	default:
+		return "SMALL"
+	}
+}
+
+// Points is the size of the font face to load for the text size
+func (s TextSize) Points() float64 {
+	switch s {
+	case textSizeLarge:
+		return 8
+	default:
+		return 6
+	}
+}
+
+// TextSize is the size of text chosen in the options, using the smallest if it
+// was never chosen
+func (g *Game) TextSize() TextSize {
+	for s := TextSize(0); s < textSizeCount; s++ {
+		if s.String() == g.Settings.TextSize {
+			return s
+		}
+	}
+	return textSizeSmall
+}
+
+// SetTextSize loads a font face for drawing messages and menus at the given
+// size, keeping the old one if it can't be loaded
+func (g *Game) SetTextSize(size TextSize) error {
+	face, err := loadFont("assets/fonts/tiny.ttf", size.Points())
+	if err != nil {
+		return err
+	}
+	g.TextFont = face
+	g.Settings.TextSize = size.String()
+	return nil
+}
+
+// How far apart lines of text in menus are, enough for the tallest letters
+// of the text font and a pixel between them
+func (g *Game) textLineHeight() int {
+	bounds, _ := font.BoundString(g.TextFont, "AJ")
+	return (bounds.Max.Y - bounds.Min.Y).Ceil() + 1
+}
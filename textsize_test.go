@@ -0,0 +1,32 @@
+// Copyright 2022 Siôn le Roux.  All rights reserved.
+// Use of this source code is subject to an MIT-style
+// licence which can be found in the LICENSE file.
+
+package main
+
+import (
+	"testing"
+
+	"golang.org/x/image/font"
+)
+
+func TestLargerTextSize(t *testing.T) {
+	g := newTestGame()
+	sizeOf := func(size TextSize) (int, int) {
+		if err := g.SetTextSize(size); err != nil {
+			t.Fatalf("SetTextSize(%v) failed: %v", size, err)
+		}
+		if got := g.TextSize(); got != size {
+			t.Fatalf("TextSize() after setting %v = %v", size, got)
+		}
+		bounds, _ := font.BoundString(g.TextFont, "PAUSED")
+		return (bounds.Max.X - bounds.Min.X).Ceil(), (bounds.Max.Y - bounds.Min.Y).Ceil()
+	}
+
+	smallW, smallH := sizeOf(textSizeSmall)
+	largeW, largeH := sizeOf(textSizeLarge)
+	if largeW <= smallW || largeH <= smallH {
+		t.Errorf("large text is %dx%d and small text %dx%d, want large text bigger both ways",
+			largeW, largeH, smallW, smallH)
+	}
+}
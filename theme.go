@@ -0,0 +1,113 @@
+// Copyright 2022 Siôn le Roux.  All rights reserved.
+// Use of this source code is subject to an MIT-style
+// licence which can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image/color"
+	"io/fs"
+	"log"
+	"os"
+	"strings"
+)
+
+// Name of the file in the config directory a colour theme is loaded from
+const themeFile = "theme.json"
+
+// Theme is a palette written by hand, with colours like "#c7f0d8", for
+// showing the game in colours other than the presets
+type Theme struct {
+	Name        string `json:"name"`
+	Light       string `json:"light"`
+	Dark        string `json:"dark"`
+	Transparent string `json:"transparent"`
+}
+
+// Read a colour written as hex digits like #rrggbb, or #rrggbbaa to give it
+// some transparency
+func parseHexColor(s string) (color.RGBA, error) {
+	digits := strings.TrimPrefix(strings.TrimSpace(s), "#")
+	if len(digits) == 6 {
+		digits += "ff"
+	}
+	if len(digits) != 8 {
+		return color.RGBA{}, fmt.Errorf("colour %q needs 6 or 8 hex digits", s)
+	}
+	b, err := hex.DecodeString(digits)
+	if err != nil {
+		return color.RGBA{}, fmt.Errorf("colour %q isn't hex: %w", s, err)
+	}
+	nrgba := color.NRGBA{b[0], b[1], b[2], b[3]}
+	return color.RGBAModel.Convert(nrgba).(color.RGBA), nil
+}
+
+// PalettePreset makes a palette preset out of the theme, keeping the colours
+// of a fallback preset for any that are missing or can't be read
+func (t Theme) PalettePreset(fallback PalettePreset) PalettePreset {
+	p := fallback
+	p.Name = strings.ToUpper(strings.TrimSpace(t.Name))
+	if p.Name == "" {
+		p.Name = "CUSTOM"
+	}
+	colours := []struct {
+		name string
+		hex  string
+		to   *color.Color
+	}{
+		{"light", t.Light, &p.Light},
+		{"dark", t.Dark, &p.Dark},
+		{"transparent", t.Transparent, &p.Transparent},
+	}
+	for _, c := range colours {
+		if c.hex == "" {
+			continue
+		}
+		clr, err := parseHexColor(c.hex)
+		if err != nil {
+			log.Printf("Using default %s colour for theme: %v\n", c.name, err)
+			continue
+		}
+		*c.to = clr
+	}
+	return p
+}
+
+// LoadTheme reads the theme file, returning an error if there isn't one or
+// it can't be decoded
+func LoadTheme() (PalettePreset, error) {
+	name, err := configPath(themeFile)
+	if err != nil {
+		return PalettePreset{}, err
+	}
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return PalettePreset{}, fmt.Errorf("reading theme %s: %w", name, err)
+	}
+	var t Theme
+	if err := json.Unmarshal(data, &t); err != nil {
+		return PalettePreset{}, fmt.Errorf("decoding theme %s: %w", name, err)
+	}
+	return t.PalettePreset(palettePresets[0]), nil
+}
+
+// Add the colour theme from the theme file to the palettes you can choose from
+// and start off showing the game in it, if there is one
+func (g *Game) addTheme() {
+	theme, err := LoadTheme()
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			log.Println("Not using theme:", err)
+		}
+		return
+	}
+	if i := palettePreset(theme.Name); palettePresets[i].Name == theme.Name {
+		theme.Name = "CUSTOM" // Don't hide a preset with the same name
+	}
+	palettePresets = append(palettePresets, theme)
+	g.Settings.Palette = theme.Name
+}
@@ -0,0 +1,72 @@
+// Copyright 2022 Siôn le Roux.  All rights reserved.
+// Use of this source code is subject to an MIT-style
+// licence which can be found in the LICENSE file.
+
+package main
+
+import (
+	"image/color"
+	"testing"
+)
+
+func TestParseHexColor(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    color.RGBA
+		wantErr bool
+	}{
+		{"#c7f0d8", color.RGBA{0xc7, 0xf0, 0xd8, 0xff}, false},
+		{"43523d", color.RGBA{0x43, 0x52, 0x3d, 0xff}, false},
+		{" #FFFFFF ", color.RGBA{0xff, 0xff, 0xff, 0xff}, false},
+		{"#ff000080", color.RGBA{0x80, 0x00, 0x00, 0x80}, false}, // Premultiplied
+		{"#00000000", color.RGBA{}, false},
+		{"#fff", color.RGBA{}, true},
+		{"#c7f0d8f", color.RGBA{}, true},
+		{"#c7f0zz", color.RGBA{}, true},
+		{"", color.RGBA{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := parseHexColor(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseHexColor(%q) error = %v, want error %v", tt.in, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("parseHexColor(%q) = %v, want %v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestThemePalettePreset(t *testing.T) {
+	fallback := PalettePreset{
+		Name:        "ORIGINAL",
+		Light:       color.RGBA{1, 1, 1, 0xff},
+		Dark:        color.RGBA{2, 2, 2, 0xff},
+		Transparent: color.RGBA{3, 3, 3, 0xff},
+	}
+	theme := Theme{
+		Name:  " dusk ",
+		Light: "#ffeedd",
+		Dark:  "not a colour",
+		// Transparent is missing
+	}
+
+	p := theme.PalettePreset(fallback)
+	if p.Name != "DUSK" {
+		t.Errorf("theme named %q, want %q", p.Name, "DUSK")
+	}
+	if want := (color.RGBA{0xff, 0xee, 0xdd, 0xff}); p.Light != want {
+		t.Errorf("light colour %v, want %v from the theme", p.Light, want)
+	}
+	if p.Dark != fallback.Dark {
+		t.Errorf("malformed dark colour became %v, want fallback %v", p.Dark, fallback.Dark)
+	}
+	if p.Transparent != fallback.Transparent {
+		t.Errorf("missing transparent colour became %v, want fallback %v", p.Transparent, fallback.Transparent)
+	}
+
+	if p := (Theme{}).PalettePreset(fallback); p.Name != "CUSTOM" {
+		t.Errorf("unnamed theme named %q, want %q", p.Name, "CUSTOM")
+	}
+}
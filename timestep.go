@@ -0,0 +1,46 @@
+// Copyright 2022 Siôn le Roux.  All rights reserved.
+// Use of this source code is subject to an MIT-style
+// licence which can be found in the LICENSE file.
+
+package main
+
+import "github.com/hajimehoshi/ebiten/v2"
+
+// LogicTPS is how many times a second the game logic runs, which all the
+// timings in ticks are based on
+const LogicTPS = 60
+
+// Timestep works out how many fixed steps of game logic to run each update so
+// that the game runs at the same speed whatever rate it's updated at
+type Timestep struct {
+	accumulator int // Time left over from earlier updates, in 1/(rate*tps) seconds
+}
+
+// Steps says how many logic steps to run for an update, when updates happen
+// updateRate times a second and logic runs logicRate times a second
+func (ts *Timestep) Steps(updateRate, logicRate int) int {
+	if updateRate <= 0 {
+		return 1
+	}
+	ts.accumulator += logicRate
+	steps := ts.accumulator / updateRate
+	ts.accumulator %= updateRate
+	return steps
+}
+
+// Work out how many logic steps to run this update at the current TPS
+func (g *Game) logicSteps() int {
+	return g.Timestep.Steps(ebiten.TPS(), LogicTPS)
+}
+
+// How fast the round is played in slow motion, in percent of normal speed
+const SlowMotionPercent = 50
+
+// Work out how many of this update's logic steps to play the round for, which
+// is fewer in slow motion to give more time to react
+func (g *Game) playSteps(steps int) int {
+	if !g.SlowMotion {
+		return steps
+	}
+	return g.SlowTimestep.Steps(100, steps*SlowMotionPercent)
+}
@@ -0,0 +1,60 @@
+// Copyright 2022 Siôn le Roux.  All rights reserved.
+// Use of this source code is subject to an MIT-style
+// licence which can be found in the LICENSE file.
+
+package main
+
+import "testing"
+
+func TestTimestepSteps(t *testing.T) {
+	tests := []struct {
+		name      string
+		tps       int
+		perUpdate []int // Steps expected for the first few updates
+	}{
+		{"60 TPS", 60, []int{1, 1, 1, 1}},
+		{"120 TPS", 120, []int{0, 1, 0, 1}},
+		{"30 TPS", 30, []int{2, 2, 2, 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ts Timestep
+			for i, want := range tt.perUpdate {
+				if got := ts.Steps(tt.tps, LogicTPS); got != want {
+					t.Errorf("update %d ran %d steps, want %d", i, got, want)
+				}
+			}
+
+			// Over a second the logic runs the same number of times
+			ts = Timestep{}
+			total := 0
+			for i := 0; i < tt.tps; i++ {
+				total += ts.Steps(tt.tps, LogicTPS)
+			}
+			if total != LogicTPS {
+				t.Errorf("ran %d steps in a second, want %d", total, LogicTPS)
+			}
+		})
+	}
+}
+
+func TestPlayStepsSlowMotion(t *testing.T) {
+	g := newTestGame()
+	g.SlowMotion = true
+	total := 0
+	for i := 0; i < 60; i++ {
+		steps := g.playSteps(1)
+		if steps > 1 {
+			t.Fatalf("update %d played %d steps in slow motion, want at most 1", i, steps)
+		}
+		total += steps
+	}
+	if want := 60 * SlowMotionPercent / 100; total != want {
+		t.Errorf("played %d steps over 60 updates in slow motion, want %d", total, want)
+	}
+
+	g.SlowMotion = false
+	if steps := g.playSteps(2); steps != 2 {
+		t.Errorf("played %d steps at normal speed, want 2", steps)
+	}
+}
@@ -5,8 +5,10 @@
 package main
 
 import (
+	"encoding/json"
 	"image"
 	"log"
+	"slices"
 
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
@@ -14,12 +16,99 @@ import (
 
 // Tower can be placed at a position to shoot Creeps
 type Tower struct {
-	Coords image.Point
-	Cost   int
-	Damage int
-	Frame  int
-	Target *Creep // the creep it's currently attacking
-	Sprite *SpriteSheet
+	Kind       TowerKind
+	Coords     image.Point
+	Cost       int
+	Invested   int // all the money spent on it, including upgrades
+	Damage     int
+	FireRate   int             // ticks to wait between shots
+	Cooldown   int             // ticks left until it can fire again
+	Bounces    int             // how many more creeps each hit jumps on to
+	CanTarget  CreepKind       // which kinds of creep it can hit
+	Poison     int             // poison damage per tick each hit adds to the creep
+	PoisonTime int             // how many ticks the poison from each hit lasts
+	Knockback  int             // how many pixels each hit pushes a creep back along its path
+	Detects    bool            // whether it reveals cloaked creeps in its range to every tower
+	Income     int             // money it makes each time it fires instead of attacking
+	Earned     int             // all the money it has made this round
+	Levels     [trackCount]int // how many times each stat has been upgraded
+	Footprint  image.Point     // how many tiles across and down it covers
+	Target     *Creep          // the creep it's currently attacking
+	Locked     bool            // whether it keeps attacking its target no matter what
+	TargetMode TargetMode      // how it picks which creep to attack
+	Chain      []*Creep        // the creeps hit by the last shot, starting with the target
+	ShotTimer  int             // ticks left to show the last shot for
+	Facing     int             // which way it faces while idle, 0 to play its idle animation
+	Dealt      int             // all the damage its shots have dealt
+	Kills      int             // how many creeps its shots have killed
+	Sprite     *SpriteSheet
+	Animation
+}
+
+// TowerKind identifies the different types of tower
+type TowerKind int
+
+const (
+	towerKindBasic TowerKind = iota
+	towerKindStrong
+	towerKindChain
+	towerKindAntiAir
+	towerKindPoison
+	towerKindCannon
+	towerKindDetector
+	towerKindBank
+)
+
+// String is the short name of the tower kind shown in the HUD
+func (k TowerKind) String() string {
+	switch k {
+	case towerKindStrong:
+		return "STRONG"
+	case towerKindChain:
+		return "CHAIN"
+	case towerKindAntiAir:
+		return "AA"
+	case towerKindPoison:
+		return "POISON"
+	case towerKindCannon:
+		return "CANNON"
+	case towerKindDetector:
+		return "RADAR"
+	case towerKindBank:
+		return "BANK"
+	default:
+		return "BASIC"
+	}
+}
+
+// The kinds of tower you can choose to build on an empty tile
+var buildPalette = []TowerKind{towerKindBasic, towerKindAntiAir, towerKindPoison, towerKindChain, towerKindCannon, towerKindDetector, towerKindBank}
+
+// NewTower makes a new tower of the given kind at the cursor position
+func NewTower(g *Game, kind TowerKind) *Tower {
+	switch kind {
+	case towerKindStrong:
+		return NewStrongTower(g)
+	case towerKindChain:
+		return NewChainTower(g)
+	case towerKindAntiAir:
+		return NewAntiAirTower(g)
+	case towerKindPoison:
+		return NewPoisonTower(g)
+	case towerKindCannon:
+		return NewCannonTower(g)
+	case towerKindDetector:
+		return NewDetectorTower(g)
+	case towerKindBank:
+		return NewBankTower(g)
+	default:
+		return NewBasicTower(g)
+	}
+}
+
+// NewPaletteTower makes a tower of the kind currently chosen for building
+func NewPaletteTower(g *Game) *Tower {
+	return NewTower(g, buildPalette[g.Palette])
 }
 
 // NewBasicTower is a convenience wrapper to make a basic-looking tower
@@ -28,7 +117,17 @@ func NewBasicTower(g *Game) *Tower {
 	if !ok {
 		log.Fatal("Failed to retrieve basic tower from game resource map")
 	}
-	return &Tower{g.Cursor.Coords, 200, 2, 0, nil, sprite}
+	return &Tower{
+		Kind:      towerKindBasic,
+		Coords:    g.Cursor.Coords,
+		Cost:      200,
+		Damage:    40,
+		FireRate:  20,
+		CanTarget: creepKindGround,
+		Footprint: image.Pt(1, 1),
+		Sprite:    sprite,
+		Animation: newTowerAnimation(sprite),
+	}
 }
 
 // NewStrongTower is a convenience wrapper to make a strong-looking tower
@@ -38,106 +137,623 @@ func NewStrongTower(g *Game) *Tower {
 	if !ok {
 		log.Fatal("Failed to retrieve strong tower from game resource map")
 	}
-	return &Tower{g.Cursor.Coords, 300, 5, 0, nil, sprite}
+	return &Tower{
+		Kind:      towerKindStrong,
+		Coords:    g.Cursor.Coords,
+		Cost:      300,
+		Damage:    50,
+		FireRate:  10,
+		CanTarget: creepKindGround,
+		Footprint: image.Pt(2, 2),
+		Sprite:    sprite,
+		Animation: newTowerAnimation(sprite),
+	}
+}
+
+// NewChainTower is a convenience wrapper to make a tower whose hits jump from
+// its target on to other creeps nearby, getting weaker with each jump
+func NewChainTower(g *Game) *Tower {
+	sprite, ok := g.Sprites[spriteTowerStrong]
+	if !ok {
+		log.Fatal("Failed to retrieve chain tower from game resource map")
+	}
+	return &Tower{
+		Kind:      towerKindChain,
+		Coords:    g.Cursor.Coords,
+		Cost:      500,
+		Damage:    60,
+		FireRate:  15,
+		Bounces:   ChainBounces,
+		CanTarget: creepKindAll,
+		Footprint: image.Pt(1, 1),
+		Sprite:    sprite,
+		Animation: newTowerAnimation(sprite),
+	}
+}
+
+// NewAntiAirTower is a convenience wrapper to make a tower that can only hit
+// flying creeps
+func NewAntiAirTower(g *Game) *Tower {
+	sprite, ok := g.Sprites[spriteTowerBasic]
+	if !ok {
+		log.Fatal("Failed to retrieve anti-air tower from game resource map")
+	}
+	return &Tower{
+		Kind:      towerKindAntiAir,
+		Coords:    g.Cursor.Coords,
+		Cost:      250,
+		Damage:    40,
+		FireRate:  10,
+		Footprint: image.Pt(1, 1),
+		CanTarget: creepKindFlying,
+		Sprite:    sprite,
+		Animation: newTowerAnimation(sprite),
+	}
+}
+
+// NewPoisonTower is a convenience wrapper to make a tower that does little
+// damage itself but poisons the creeps it hits
+func NewPoisonTower(g *Game) *Tower {
+	sprite, ok := g.Sprites[spriteTowerBasic]
+	if !ok {
+		log.Fatal("Failed to retrieve poison tower from game resource map")
+	}
+	return &Tower{
+		Kind:       towerKindPoison,
+		Coords:     g.Cursor.Coords,
+		Cost:       150,
+		Damage:     5,
+		FireRate:   30,
+		Footprint:  image.Pt(1, 1),
+		CanTarget:  creepKindGround,
+		Poison:     1,
+		PoisonTime: 3 * 60,
+		Sprite:     sprite,
+		Animation:  newTowerAnimation(sprite),
+	}
+}
+
+// NewCannonTower is a convenience wrapper to make a slow tower whose hits
+// knock creeps back along their path, holding them up
+func NewCannonTower(g *Game) *Tower {
+	sprite, ok := g.Sprites[spriteTowerBasic]
+	if !ok {
+		log.Fatal("Failed to retrieve cannon tower from game resource map")
+	}
+	return &Tower{
+		Kind:      towerKindCannon,
+		Coords:    g.Cursor.Coords,
+		Cost:      350,
+		Damage:    30,
+		FireRate:  45,
+		Knockback: 4,
+		Footprint: image.Pt(1, 1),
+		CanTarget: creepKindGround,
+		Sprite:    sprite,
+		Animation: newTowerAnimation(sprite),
+	}
+}
+
+// NewDetectorTower is a convenience wrapper to make a weak tower that reveals
+// cloaked creeps in its range so that every tower can attack them
+func NewDetectorTower(g *Game) *Tower {
+	sprite, ok := g.Sprites[spriteTowerBasic]
+	if !ok {
+		log.Fatal("Failed to retrieve detector tower from game resource map")
+	}
+	return &Tower{
+		Kind:      towerKindDetector,
+		Coords:    g.Cursor.Coords,
+		Cost:      200,
+		Damage:    10,
+		FireRate:  30,
+		Detects:   true,
+		Footprint: image.Pt(1, 1),
+		CanTarget: creepKindAll,
+		Sprite:    sprite,
+		Animation: newTowerAnimation(sprite),
+	}
+}
+
+// How much money a bank tower can make in one round
+const BankIncomeCap = 300
+
+// NewBankTower is a convenience wrapper to make a tower that doesn't attack
+// but makes a little money every so often, up to a limit each round
+func NewBankTower(g *Game) *Tower {
+	sprite, ok := g.Sprites[spriteTowerBasic]
+	if !ok {
+		log.Fatal("Failed to retrieve bank tower from game resource map")
+	}
+	return &Tower{
+		Kind:      towerKindBank,
+		Coords:    g.Cursor.Coords,
+		Cost:      300,
+		FireRate:  2 * 60,
+		Cooldown:  2 * 60,
+		Income:    10,
+		Footprint: image.Pt(1, 1),
+		Sprite:    sprite,
+		Animation: newTowerAnimation(sprite),
+	}
+}
+
+// How much of the money spent on a tower you get back when selling it
+const SellRefundPercent = 50
+
+// SellValue is how much money you get back for selling the tower
+func (t *Tower) SellValue() int {
+	return t.Invested * SellRefundPercent / 100
+}
+
+// CanHit says whether the tower is able to attack a kind of creep
+func (t *Tower) CanHit(c *Creep) bool {
+	return t.CanTarget&c.Kind != 0
+}
+
+// How close a cloaked creep has to get to a tower for it to see it, which is
+// about as far as the tiles next to it
+const RevealRadius = 9
+
+// Revealed says whether every tower can see a creep, which they can unless
+// it's cloaked and not in range of a detector tower
+func (g *Game) Revealed(c *Creep) bool {
+	if !c.Cloaked {
+		return true
+	}
+	for _, t := range g.Towers {
+		if t.Detects && inRange(t, c) {
+			return true
+		}
+	}
+	return false
+}
+
+// CanSee says whether the tower can see a creep to attack it, cloaked
+// creeps have to come close unless they've been revealed
+func (t *Tower) CanSee(g *Game, c *Creep) bool {
+	if distanceSquared(t.Coords, c.Coords) <= RevealRadius*RevealRadius {
+		return true
+	}
+	return g.Revealed(c)
+}
+
+// Upgrade makes the tower this one can be upgraded into in the same place,
+// keeping its upgrades, record and settings, or nil if it can't be upgraded
+func (t *Tower) Upgrade(g *Game) *Tower {
+	if t.Kind != towerKindBasic {
+		return nil
+	}
+	tu := NewStrongTower(g)
+	tu.Coords = t.Coords
+	tu.Invested = t.Invested
+	tu.Earned = t.Earned
+	tu.Levels = t.Levels
+	tu.Target = t.Target
+	tu.Locked = t.Locked
+	tu.TargetMode = t.TargetMode
+	tu.Facing = t.Facing
+	tu.Dealt = t.Dealt
+	tu.Kills = t.Kills
+	return tu
+}
+
+// UpgradeCost is how much upgrading the tower to the next kind costs, or -1 if
+// it can't be upgraded
+func (t *Tower) UpgradeCost(g *Game) int {
+	tu := t.Upgrade(g)
+	if tu == nil {
+		return -1
+	}
+	return tu.Cost
+}
+
+// Start a tower off playing its construction animation
+func newTowerAnimation(sprite *SpriteSheet) Animation {
+	return NewAnimation(sprite.TagOrAll(towerTagConstruction), false)
+}
+
+// BuyResult is what happened when trying to buy a tower
+type BuyResult int
+
+const (
+	buyBuilt BuyResult = iota
+	buyUpgraded
+	buyRejectedNoBuild
+	buyRejectedOccupied
+	buyRejectedNoRoom
+	buyRejectedFunds
+	buyRejectedCooldown
+)
+
+// Rejected says whether the tower wasn't bought
+func (r BuyResult) Rejected() bool {
+	return r != buyBuilt && r != buyUpgraded
+}
+
+// String describes the result for the log
+func (r BuyResult) String() string {
+	switch r {
+	case buyUpgraded:
+		return "Tower upgraded"
+	case buyRejectedNoBuild:
+		return "Building not allowed here"
+	case buyRejectedOccupied:
+		return "Tower can't be upgraded"
+	case buyRejectedNoRoom:
+		return "No room to build here"
+	case buyRejectedFunds:
+		return "Not enough money"
+	case buyRejectedCooldown:
+		return "Still building"
+	default:
+		return "Tower built"
+	}
 }
 
-// BuyTower buys a tower at the cursor position if possible
-func BuyTower(g *Game) {
-	t := NewBasicTower(g)
+// BuyTower buys a tower at the cursor position if possible, and says whether
+// it was built, upgraded or why it couldn't be. Nothing is bought while the
+// cursor is hidden after building so a tower isn't upgraded by accident
+func BuyTower(g *Game) BuyResult {
+	if g.Cursor.Cooldown > 0 {
+		return buyRejectedCooldown
+	}
+	return buyTowerAt(g, g.Cursor.Coords)
+}
+
+// Buy a tower of the kind chosen for building at the given coordinates
+func buyTowerAt(g *Game, coords image.Point) BuyResult {
+	t := NewPaletteTower(g)
+	t.Coords = coords
 	moneydiff := g.Money - t.Cost
-	tileSize := 7
-	hudMargin := 5
-	var nobuild bool
+	if !IsBuildable(g, t.Coords) {
+		return buyRejectedNoBuild
+	}
+	if k := IsOccupied(g, t.Coords); k != -1 {
+		log.Println("Building space occupied")
+		tu := g.Towers[k].Upgrade(g)
+		if tu == nil {
+			return buyRejectedOccupied
+		}
+		if !HasRoom(g, tu.Coords, tu.Footprint, k) {
+			return buyRejectedNoRoom
+		}
+		upgradediff := g.Money - tu.Cost
+		if upgradediff < 0 {
+			return buyRejectedFunds
+		}
+		log.Printf("Upgrading tower %d - %d = %d\n", g.Money, tu.Cost, upgradediff)
+		tu.Invested = g.Towers[k].Invested + tu.Cost
+		g.recordPlacement(tu, g.Towers[k])
+		g.Towers[k] = tu
+		g.Money = upgradediff
+		g.TotalSpent += tu.Cost
+		g.Cursor.StartCooldown(tu.Coords, 10)
+		return buyUpgraded
+	}
+	if !HasRoom(g, t.Coords, t.Footprint, -1) {
+		return buyRejectedNoRoom
+	}
+	if moneydiff < 0 {
+		return buyRejectedFunds
+	}
+	log.Printf("Buying tower %d - %d = %d\n", g.Money, t.Cost, moneydiff)
+	t.Invested = t.Cost
+	g.Towers = append(g.Towers, t)
+	g.recordPlacement(t, nil)
+	g.Money = moneydiff
+	g.TotalSpent += t.Cost
+	g.Cursor.StartCooldown(t.Coords, 11)
+	return buyBuilt
+}
+
+// IsBuildable says whether the tile at the given coordinates is one you can
+// build on, i.e. it's not one of the map's no-build tiles
+func IsBuildable(g *Game, coords image.Point) bool {
 	for _, v := range g.NoBuild {
-		nobuild = image.Rect(
-			v.X*tileSize,
-			v.Y*tileSize+hudMargin,
-			v.X*tileSize+tileSize,
-			v.Y*tileSize+tileSize+hudMargin,
-		).Overlaps(image.Rectangle{
-			t.Coords.Add(image.Pt(-2, -2)),
-			t.Coords.Add(image.Pt(2, 2)),
+		nobuild := noBuildRect(v).Overlaps(image.Rectangle{
+			coords.Add(image.Pt(-2, -2)),
+			coords.Add(image.Pt(2, 2)),
 		})
-		if nobuild == true {
-			log.Println("Building not allowed here")
-			return
+		if nobuild {
+			return false
 		}
 	}
-	for k, v := range g.Towers {
-		if v.Coords == t.Coords {
-			log.Println("Building space occupied")
-			tu := NewStrongTower(g)
-			upgradediff := g.Money - tu.Cost
-			if upgradediff >= 0 {
-				log.Printf("Upgrading tower %d - %d = %d\n", g.Money, tu.Cost, upgradediff)
-				g.Towers[k] = tu
-				g.Money = upgradediff
-				g.Cursor.Cooldown = 10
+	return true
+}
+
+// The area of the map covered by a no-build tile
+func noBuildRect(v *Waypoint) image.Rectangle {
+	tileSize := 7
+	hudMargin := 5
+	return image.Rect(
+		v.X*tileSize,
+		v.Y*tileSize+hudMargin,
+		v.X*tileSize+tileSize,
+		v.Y*tileSize+tileSize+hudMargin,
+	)
+}
+
+// HasRoom says whether every tile of a footprint starting at the given tile
+// is inside the map, buildable and not covered by a tower other than the one
+// being replaced, given by its index or -1 for none
+func HasRoom(g *Game, coords image.Point, footprint image.Point, replacing int) bool {
+	tileSize := 7
+	for y := 0; y < footprint.Y; y++ {
+		for x := 0; x < footprint.X; x++ {
+			tile := coords.Add(image.Pt(x*tileSize, y*tileSize))
+			if !insideMap(g, tile) || !IsBuildable(g, tile) {
+				return false
+			}
+			if k := IsOccupied(g, tile); k != -1 && k != replacing {
+				return false
 			}
-			return
 		}
 	}
-	if moneydiff >= 0 {
-		log.Printf("Buying tower %d - %d = %d\n", g.Money, t.Cost, moneydiff)
-		g.Towers = append(g.Towers, t)
-		g.Money = moneydiff
-		g.Cursor.Cooldown = 11
-	}
+	return true
 }
 
-// IsOccupied says whether the current tile is already occupied by a tower
+// IsOccupied says whether the current tile is already occupied by a tower,
+// returning the index of the tower covering it or -1
 func IsOccupied(g *Game, coords image.Point) int {
 	for k, v := range g.Towers {
-		if v.Coords == coords {
+		if coords.In(v.Bounds()) {
 			return k
 		}
 	}
 	return -1
 }
 
+// Bounds is the area of the map covered by the tower's footprint, with its
+// coordinates being in the top-left tile
+func (t *Tower) Bounds() image.Rectangle {
+	tileSize := 7
+	hudMargin := 5
+	corner := image.Pt(
+		t.Coords.X/tileSize*tileSize,
+		(t.Coords.Y-hudMargin)/tileSize*tileSize+hudMargin,
+	)
+	return image.Rectangle{corner, corner.Add(t.Footprint.Mul(tileSize))}
+}
+
+// Names of the frame tags of tower animations in the sprite file
+const (
+	towerTagConstruction = "construction"
+	towerTagIdle         = "idle"
+	towerTagFiring       = "firing"
+)
+
 // Update handles game logic for towers
 func (t *Tower) Update(g *Game) error {
-	// Construction animation
-	if t.Frame < len(t.Sprite.Sprite)-1 {
-		t.Frame++
+	t.animate()
+
+	if t.Income > 0 {
+		t.makeMoney(g)
+		return nil
 	}
 
 	// Target Seeking
+	if t.Target != nil && t.Target.Health <= 0 {
+		t.Target = nil
+	}
 	if t.Target == nil {
+		t.Locked = false
 		t.findNewTarget(g)
 	} else {
-		t.clearIfOutOfRange()
+		t.clearIfOutOfRange(g)
 	}
 
 	// Damage dealing
-	if t.Target != nil {
-		died := t.Target.Attack(t.Damage)
-		if died {
-			t.Target = nil
+	if t.ShotTimer > 0 {
+		t.ShotTimer--
+	} else {
+		t.Chain = nil
+	}
+	if t.Cooldown > 0 {
+		t.Cooldown--
+	}
+	if t.Target != nil && t.Cooldown == 0 && !t.Locked {
+		t.findNewTarget(g) // Pick the best creep again before each shot unless locked
+	}
+	if t.Target != nil && t.Cooldown == 0 {
+		t.Cooldown = t.Stats().FireRate
+		t.ShotTimer = shotDuration
+		t.Chain = t.findChain(g)
+		g.forgetPlacements()
+		for i, c := range t.Chain {
+			if t.Poison > 0 {
+				c.Poison(t.Poison, t.PoisonTime)
+			}
+			alive := c.Health > 0
+			damage := chainDamage(t.Stats().Damage, i)
+			died := c.Attack(damage)
+			t.recordHit(damage, died && alive)
+			if t.Knockback > 0 && !died {
+				c.KnockBack(g, t.Knockback)
+			}
+			if died && c == t.Target {
+				t.Target = nil
+				t.Locked = false
+			}
 		}
 	}
 
 	return nil
 }
 
-// Look for the first creep in range
+// Add a hit to the tower's stats, counting a kill if it finished the creep off
+func (t *Tower) recordHit(damage int, killed bool) {
+	t.Dealt += damage
+	if killed {
+		t.Kills++
+	}
+}
+
+// Pay out the tower's income each time it would fire, until it's made as
+// much as it can this round
+func (t *Tower) makeMoney(g *Game) {
+	if t.Cooldown > 0 {
+		t.Cooldown--
+	}
+	if t.Cooldown > 0 {
+		return
+	}
+	t.Cooldown = t.Stats().FireRate
+	income := min(t.Income, BankIncomeCap-t.Earned)
+	if income <= 0 {
+		return
+	}
+	t.Earned += income
+	g.earn(income)
+}
+
+// How many ticks a shot stays on screen after it's fired
+const shotDuration = 4
+
+// Chain hits jump between creeps close to each other, losing damage each time
+const (
+	ChainBounces      = 3  // How many times a chain tower's hit jumps
+	ChainRadius       = 10 // How far a hit can jump to the next creep
+	ChainDecayPercent = 50 // How much of the damage is left after each jump
+)
+
+// Work out the damage dealt to a creep after a hit has bounced some times
+func chainDamage(damage int, bounce int) int {
+	for i := 0; i < bounce; i++ {
+		damage = damage * ChainDecayPercent / 100
+	}
+	return damage
+}
+
+// Find the creeps hit by a shot at the current target, with each jump going
+// to the closest creep that hasn't been hit yet
+func (t *Tower) findChain(g *Game) []*Creep {
+	chain := []*Creep{t.Target}
+	for len(chain) <= t.Bounces {
+		last := chain[len(chain)-1]
+		var next *Creep
+		for _, c := range g.Creeps {
+			d := distanceSquared(last.Coords, c.Coords)
+			if c.Health <= 0 || !t.CanHit(c) || !t.CanSee(g, c) || d > ChainRadius*ChainRadius || slices.Contains(chain, c) {
+				continue
+			}
+			if next == nil || d < distanceSquared(last.Coords, next.Coords) {
+				next = c
+			}
+		}
+		if next == nil {
+			break
+		}
+		chain = append(chain, next)
+	}
+	return chain
+}
+
+// Names of the frame tags in the sprites of towers facing each direction
+const (
+	towerTagGroundToSky = "ground_to_sky"
+	towerTagShot        = "shot"
+)
+
+// Says whether the tower can be drawn facing a direction, which only small
+// towers can do once they've been built
+func (t *Tower) canFace() bool {
+	constructing := t.Tag == t.Sprite.TagOrAll(towerTagConstruction) && !t.Finished()
+	return !constructing && t.Footprint == image.Pt(1, 1)
+}
+
+// Says whether the tower should be drawn facing its target
+func (t *Tower) facesTarget() bool {
+	return t.Target != nil && t.canFace()
+}
+
+// Sprites of a tower facing each way it can be rotated to, going clockwise
+var facingSprites = []SpriteType{
+	spriteTowerBottom,
+	spriteTowerLeft,
+	spriteTowerUp,
+	spriteTowerRight,
+}
+
+// Rotate turns the tower clockwise to the next way it can face while idle,
+// going back to its idle animation after facing every way
+func (t *Tower) Rotate() {
+	t.Facing = (t.Facing + 1) % (len(facingSprites) + 1)
+}
+
+// Choose the sprite of a tower facing up, down, left or right, whichever is
+// closest to the direction of an offset from the tower
+func facingSprite(offset image.Point) SpriteType {
+	if abs(offset.X) > abs(offset.Y) {
+		if offset.X < 0 {
+			return spriteTowerLeft
+		}
+		return spriteTowerRight
+	}
+	if offset.Y < 0 {
+		return spriteTowerUp
+	}
+	return spriteTowerBottom
+}
+
+// Absolute value of an integer
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+// Play the construction animation once, then loop the idle or firing
+// animation depending on whether the tower has something to shoot at
+func (t *Tower) animate() {
+	s := t.Sprite
+	if t.Tag != s.TagOrAll(towerTagConstruction) || t.Finished() {
+		tag := s.TagOrAll(towerTagIdle)
+		if t.Target != nil {
+			tag = s.TagOrAll(towerTagFiring)
+		}
+		t.Play(tag, true)
+	}
+	t.Step(t.Sprite.Sprite)
+}
+
+// TargetMode decides which creep a tower attacks when several are in range
+type TargetMode int
+
+const (
+	targetModeFirst   TargetMode = iota // The one closest to reaching the base
+	targetModeClosest                   // The one closest to the tower
+	targetModeCount                     // How many modes there are
+)
+
+// String is the short name of the mode shown in the HUD
+func (m TargetMode) String() string {
+	switch m {
+	case targetModeClosest:
+		return "NEAR"
+	default:
+		return "FIRST"
+	}
+}
+
+// Says whether the tower would rather attack creep a than creep b
+func (t *Tower) prefers(g *Game, a, b *Creep) bool {
+	switch t.TargetMode {
+	case targetModeClosest:
+		return distanceSquared(t.Coords, a.Coords) < distanceSquared(t.Coords, b.Coords)
+	default:
+		return furtherAlong(g, a, b)
+	}
+}
+
+// Look for the best creep in range according to the targeting mode
 func (t *Tower) findNewTarget(g *Game) {
-	tileSize := 7
-	rangeSize := 2 * tileSize
 	for _, v := range g.Creeps {
-		hitboxRadius := 3
-		creepBox := image.Rectangle{
-			v.Coords.Add(image.Pt(-hitboxRadius, -hitboxRadius)),
-			v.Coords.Add(image.Pt(hitboxRadius, hitboxRadius)),
-		}
-		towerBox := image.Rect(
-			t.Coords.X-rangeSize,
-			t.Coords.Y-rangeSize,
-			t.Coords.X+rangeSize,
-			t.Coords.Y+rangeSize,
-		)
-		withinRange := towerBox.Overlaps(creepBox)
-		if withinRange {
+		if !t.CanHit(v) || !t.CanSee(g, v) {
+			continue
+		}
+		if inRange(t, v) && (t.Target == nil || t.prefers(g, v, t.Target)) {
 			t.Target = v
 		}
 	}
@@ -150,57 +766,188 @@ func (t *Tower) cullDeadCreep() {
 	}
 }
 
-// Clear current target when it gets out of range
-func (t *Tower) clearIfOutOfRange() {
+// Clear current target when it gets out of range or out of sight
+func (t *Tower) clearIfOutOfRange(g *Game) {
+	if !inRange(t, t.Target) || !t.CanSee(g, t.Target) {
+		t.Target = nil
+		t.Locked = false
+	}
+}
+
+// CycleLock locks the tower on to the next creep it can attack after its
+// current target, or unlocks it once it's been through all of them
+func (t *Tower) CycleLock(g *Game) {
+	start := slices.Index(g.Creeps, t.Target)
+	if !t.Locked && t.Target != nil {
+		t.Locked = true
+		return
+	}
+	for i := start + 1; i < len(g.Creeps); i++ {
+		c := g.Creeps[i]
+		if c.Health > 0 && t.CanHit(c) && t.CanSee(g, c) && inRange(t, c) {
+			t.Target = c
+			t.Locked = true
+			return
+		}
+	}
+	t.Locked = false
+}
+
+// Centre is the middle of the tower's footprint on the map, which for towers
+// covering more than one tile is between the tiles rather than in the top-left
+// one
+func (t *Tower) Centre() image.Point {
 	tileSize := 7
-	rangeSize := 2 * tileSize
-	hitboxRadius := 3
-	creepBox := image.Rectangle{
-		t.Target.Coords.Add(image.Pt(-hitboxRadius, -hitboxRadius)),
-		t.Target.Coords.Add(image.Pt(hitboxRadius, hitboxRadius)),
-	}
-	towerBox := image.Rect(
-		t.Coords.X-rangeSize,
-		t.Coords.Y-rangeSize,
-		t.Coords.X+rangeSize,
-		t.Coords.Y+rangeSize,
+	return t.Coords.Add(t.Footprint.Sub(image.Pt(1, 1)).Mul(tileSize).Div(2))
+}
+
+// The area a tower can shoot into, a square reaching its range from its
+// centre in every direction
+func towerBox(t *Tower) image.Rectangle {
+	rangeSize := t.Stats().Range
+	centre := t.Centre()
+	return image.Rect(
+		centre.X-rangeSize,
+		centre.Y-rangeSize,
+		centre.X+rangeSize,
+		centre.Y+rangeSize,
 	)
-	withinRange := towerBox.Overlaps(creepBox)
-	if !withinRange {
-		t.Target = nil
+}
+
+// How far from its middle a creep can be hit if its kind doesn't say
+const DefaultHitboxRadius = 3
+
+// The area a creep can be hit in, which is bigger for bigger creeps
+func creepBox(c *Creep) image.Rectangle {
+	hitboxRadius := c.HitboxRadius
+	if hitboxRadius <= 0 {
+		hitboxRadius = DefaultHitboxRadius
+	}
+	return image.Rectangle{
+		c.Coords.Add(image.Pt(-hitboxRadius, -hitboxRadius)),
+		c.Coords.Add(image.Pt(hitboxRadius, hitboxRadius)),
 	}
 }
 
+// Says whether a creep is within a tower's range, which is when their boxes
+// overlap, so a creep just touching the edge of the range is out of it
+func inRange(t *Tower, c *Creep) bool {
+	return towerBox(t).Overlaps(creepBox(c))
+}
+
 // Draw draws the Tower to the screen
 func (t *Tower) Draw(g *Game, screen *ebiten.Image) {
 
-	// Draw tower
+	// Draw tower, turned towards its target if it has one or whichever way
+	// it was rotated to if it doesn't
 	s := t.Sprite
-	frame := s.Sprite[t.Frame]
-	op := &ebiten.DrawImageOptions{}
-	op.GeoM.Translate(
-		float64(t.Coords.X-frame.Position.W/2),
-		float64(t.Coords.Y-frame.Position.W/2),
-	)
-	screen.DrawImage(s.Image.SubImage(image.Rect(
-		frame.Position.X,
-		frame.Position.Y,
-		frame.Position.X+frame.Position.W,
-		frame.Position.Y+frame.Position.H,
-	)).(*ebiten.Image), op)
-
-	// Draw shooting laser
-	if t.Target != nil {
-		c := t.Target
+	index := t.CurrentFrame()
+	if t.facesTarget() {
+		s = g.Sprites[facingSprite(t.Target.Coords.Sub(t.Coords))]
+		shot := s.TagOrAll(towerTagShot)
+		index = shot.To
+		if t.ShotTimer > 0 {
+			index = shot.From
+		}
+	} else if t.Facing > 0 && t.canFace() {
+		s = g.Sprites[facingSprites[t.Facing-1]]
+		index = s.TagOrAll(towerTagShot).To
+	}
+	pos := g.ScreenCoords(t.Centre())
+	if i, ok := clampFrame(s, index); ok {
+		frame := s.Sprite[i]
+		op := &ebiten.DrawImageOptions{}
+		op.GeoM.Translate(
+			float64(pos.X-frame.Position.W/2),
+			float64(pos.Y-frame.Position.W/2),
+		)
+		screen.DrawImage(s.Image.SubImage(image.Rect(
+			frame.Position.X,
+			frame.Position.Y,
+			frame.Position.X+frame.Position.W,
+			frame.Position.Y+frame.Position.H,
+		)).(*ebiten.Image), op)
+	}
+
+	// Mark towers locked on to their target with a dot in the corner
+	if t.Locked {
+		corner := g.ScreenCoords(t.Bounds().Min)
+		screen.Set(corner.X, corner.Y, ColorDark)
+	}
+
+	// Draw shooting laser, jumping through every creep it hit
+	from := pos
+	for _, c := range t.Chain {
+		target := g.ScreenCoords(c.Coords)
 		ebitenutil.DrawLine(screen,
-			float64(t.Coords.X),
-			float64(t.Coords.Y),
-			float64(c.Coords.X),
-			float64(c.Coords.Y),
+			float64(from.X),
+			float64(from.Y),
+			float64(target.X),
+			float64(target.Y),
 			ColorDark,
 		)
+		from = target
 	}
 }
 
+// The part of a tower that's saved, the rest comes from its kind
+type towerJSON struct {
+	Kind       TowerKind       `json:"kind"`
+	X          int             `json:"x"`
+	Y          int             `json:"y"`
+	TargetMode TargetMode      `json:"target_mode"`
+	Levels     [trackCount]int `json:"levels"`
+	Invested   int             `json:"invested"`
+	Facing     int             `json:"facing"`
+	Earned     int             `json:"earned"`
+}
+
+// MarshalJSON saves the tower by its kind instead of its sprite so it can be
+// loaded again by a different run of the game
+func (t Tower) MarshalJSON() ([]byte, error) {
+	return json.Marshal(towerJSON{
+		Kind:       t.Kind,
+		X:          t.Coords.X,
+		Y:          t.Coords.Y,
+		TargetMode: t.TargetMode,
+		Levels:     t.Levels,
+		Invested:   t.Invested,
+		Facing:     t.Facing,
+		Earned:     t.Earned,
+	})
+}
+
+// UnmarshalJSON loads a saved tower, which needs to be rehydrated before it
+// can be used in the game
+func (t *Tower) UnmarshalJSON(data []byte) error {
+	var tj towerJSON
+	if err := json.Unmarshal(data, &tj); err != nil {
+		return err
+	}
+	*t = Tower{
+		Kind:       tj.Kind,
+		Coords:     image.Pt(tj.X, tj.Y),
+		TargetMode: tj.TargetMode,
+		Levels:     tj.Levels,
+		Invested:   tj.Invested,
+		Facing:     tj.Facing,
+		Earned:     tj.Earned,
+	}
+	return nil
+}
+
+// Rehydrate makes a tower ready for the game from a loaded one, filling in
+// its stats and sprite from its kind
+func (t *Tower) Rehydrate(g *Game) *Tower {
+	tr := NewTower(g, t.Kind)
+	tr.Coords = t.Coords
+	tr.TargetMode = t.TargetMode
+	tr.Levels = t.Levels
+	tr.Facing = t.Facing
+	tr.Invested = t.Invested
+	tr.Earned = t.Earned
+	return tr
+}
+
 // Towers is a slice of Tower entities
 type Towers []*Tower
@@ -0,0 +1,477 @@
+// Copyright 2022 Siôn le Roux.  All rights reserved.
+// Use of this source code is subject to an MIT-style
+// licence which can be found in the LICENSE file.
+
+package main
+
+import (
+	"image"
+	"testing"
+)
+
+func TestInRange(t *testing.T) {
+	g := newTestGame()
+	tower := NewTower(g, towerKindBasic)
+	tower.Coords = image.Pt(30, 30)
+	r := tower.Stats().Range
+	hitbox := 3
+
+	tests := []struct {
+		name   string
+		coords image.Point
+		want   bool
+	}{
+		{"on the tower", image.Pt(30, 30), true},
+		{"overlapping the right edge", image.Pt(30+r+hitbox-1, 30), true},
+		{"touching the right edge", image.Pt(30+r+hitbox, 30), false},
+		{"just out to the right", image.Pt(30+r+hitbox+1, 30), false},
+		{"overlapping the top edge", image.Pt(30, 30-r-hitbox+1), true},
+		{"touching the top edge", image.Pt(30, 30-r-hitbox), false},
+		{"overlapping a corner", image.Pt(30-r-hitbox+1, 30+r+hitbox-1), true},
+		{"just out past a corner", image.Pt(30-r-hitbox, 30+r+hitbox), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Creep{Coords: tt.coords, HitboxRadius: hitbox}
+			if got := inRange(tower, c); got != tt.want {
+				t.Errorf("inRange with creep at %v = %v, want %v", tt.coords, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFacingSprite(t *testing.T) {
+	tests := []struct {
+		name   string
+		offset image.Point
+		want   SpriteType
+	}{
+		{"left", image.Pt(-5, 2), spriteTowerLeft},
+		{"right", image.Pt(5, -2), spriteTowerRight},
+		{"up", image.Pt(2, -5), spriteTowerUp},
+		{"down", image.Pt(-2, 5), spriteTowerBottom},
+		{"diagonal", image.Pt(3, 3), spriteTowerBottom},
+		{"on the tower", image.Pt(0, 0), spriteTowerBottom},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := facingSprite(tt.offset); got != tt.want {
+				t.Errorf("facingSprite(%v) = %v, want %v", tt.offset, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInRangeBigFootprint(t *testing.T) {
+	g := newTestGame()
+	tower := NewStrongTower(g)
+	tower.Coords = image.Pt(30, 30)
+	tower.Footprint = image.Pt(2, 2)
+	r := tower.Stats().Range
+	hitbox := 3
+	centre := image.Pt(30+7/2, 30+7/2)
+
+	if got := tower.Centre(); got != centre {
+		t.Fatalf("Centre() = %v, want %v", got, centre)
+	}
+	tests := []struct {
+		name   string
+		coords image.Point
+		want   bool
+	}{
+		{"overlapping the bottom-right corner", centre.Add(image.Pt(r+hitbox-1, r+hitbox-1)), true},
+		{"touching the bottom-right corner", centre.Add(image.Pt(r+hitbox, r+hitbox)), false},
+		{"overlapping the top-left corner", centre.Sub(image.Pt(r+hitbox-1, r+hitbox-1)), true},
+		{"touching the top-left corner", centre.Sub(image.Pt(r+hitbox, r+hitbox)), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Creep{Coords: tt.coords, HitboxRadius: hitbox}
+			if got := inRange(tower, c); got != tt.want {
+				t.Errorf("inRange with creep at %v = %v, want %v", tt.coords, got, tt.want)
+			}
+		})
+	}
+}
+
+// The coordinates the cursor has when it's on a tile
+func tileCoords(x, y int) image.Point {
+	tileSize := 7
+	hudOffset := 6
+	tileCenter := 3
+	return image.Pt(x*tileSize+tileCenter, y*tileSize+tileCenter+hudOffset)
+}
+
+// Put a tower of some kind on a tile
+func placeTower(g *Game, kind TowerKind, x, y int) {
+	t := NewTower(g, kind)
+	t.Coords = tileCoords(x, y)
+	g.Towers = append(g.Towers, t)
+}
+
+func TestBuyTower(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(g *Game)
+		want  BuyResult
+		spent int
+	}{
+		{"built on an empty tile", func(g *Game) {}, buyBuilt, 200},
+		{"upgraded a basic tower", func(g *Game) {
+			placeTower(g, towerKindBasic, 2, 2)
+		}, buyUpgraded, 300},
+		{"on a no-build tile", func(g *Game) {
+			g.Cursor.Coords = tileCoords(4, 3)
+		}, buyRejectedNoBuild, 0},
+		{"on a tower that can't be upgraded", func(g *Game) {
+			placeTower(g, towerKindStrong, 2, 2)
+		}, buyRejectedOccupied, 0},
+		{"on the right of a 2x2 tower", func(g *Game) {
+			placeTower(g, towerKindStrong, 2, 2)
+			g.Cursor.Coords = tileCoords(3, 2)
+		}, buyRejectedOccupied, 0},
+		{"under a 2x2 tower", func(g *Game) {
+			placeTower(g, towerKindStrong, 2, 2)
+			g.Cursor.Coords = tileCoords(2, 3)
+		}, buyRejectedOccupied, 0},
+		{"on the far corner of a 2x2 tower", func(g *Game) {
+			placeTower(g, towerKindStrong, 2, 2)
+			g.Cursor.Coords = tileCoords(3, 3)
+		}, buyRejectedOccupied, 0},
+		{"just past a 2x2 tower", func(g *Game) {
+			placeTower(g, towerKindStrong, 2, 2)
+			g.Cursor.Coords = tileCoords(4, 2)
+		}, buyBuilt, 200},
+		{"upgrading next to another tower", func(g *Game) {
+			placeTower(g, towerKindBasic, 2, 2)
+			placeTower(g, towerKindBasic, 3, 2)
+		}, buyRejectedNoRoom, 0},
+		{"without enough money", func(g *Game) {
+			g.Money = 199
+		}, buyRejectedFunds, 0},
+		{"while the cursor is cooling down", func(g *Game) {
+			g.Cursor.StartCooldown(g.Cursor.Coords, 10)
+		}, buyRejectedCooldown, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := newTestGame()
+			g.Cursor.Coords = tileCoords(2, 2)
+			tt.setup(g)
+			money := g.Money
+			if got := BuyTower(g); got != tt.want {
+				t.Errorf("BuyTower() = %v, want %v", got, tt.want)
+			}
+			if spent := money - g.Money; spent != tt.spent {
+				t.Errorf("BuyTower() spent %d, want %d", spent, tt.spent)
+			}
+		})
+	}
+}
+
+func TestBuyTwiceWithinCooldown(t *testing.T) {
+	g := newTestGame()
+	g.Cursor.Coords = tileCoords(2, 2)
+	start := g.Money
+
+	if got := BuyTower(g); got != buyBuilt {
+		t.Fatalf("first BuyTower = %v, want %v", got, buyBuilt)
+	}
+	if got := BuyTower(g); got != buyRejectedCooldown {
+		t.Errorf("second BuyTower straight after = %v, want %v", got, buyRejectedCooldown)
+	}
+	if len(g.Towers) != 1 || g.Towers[0].Kind != towerKindBasic || g.Money != start-200 {
+		t.Errorf("%d towers and spent %d after building twice in a row, want one basic tower for 200",
+			len(g.Towers), start-g.Money)
+	}
+
+	g.Cursor.Cooldown = 0
+	if got := BuyTower(g); got != buyUpgraded {
+		t.Errorf("BuyTower after the cooldown = %v, want %v", got, buyUpgraded)
+	}
+}
+
+func TestAntiAirIgnoresGroundCreeps(t *testing.T) {
+	g := newTestGame()
+	tower := NewAntiAirTower(g)
+	tower.Coords = image.Pt(30, 30)
+	ground := NewSmallCreep(g)
+	ground.Coords = tower.Coords
+	g.Creeps = Creeps{ground}
+	g.Towers = Towers{tower}
+
+	health := ground.Health
+	for i := 0; i < 30; i++ {
+		tower.Update(g)
+	}
+	if tower.Target != nil || ground.Health != health {
+		t.Errorf("anti-air tower targeted %v and left ground creep on %d health, want no target and %d health",
+			tower.Target, ground.Health, health)
+	}
+
+	flyer := NewFlyingCreep(g)
+	flyer.Coords = tower.Coords
+	g.Creeps = append(g.Creeps, flyer)
+	tower.Update(g)
+	if tower.Target != flyer {
+		t.Errorf("anti-air tower targeted %v, want the flying creep", tower.Target)
+	}
+}
+
+func TestTargetFirst(t *testing.T) {
+	g := newTestGame()
+	corner := WaypointCoords(testMapData.Ways[1])
+	tower := NewBasicTower(g)
+	tower.Coords = corner
+	tower.TargetMode = targetModeFirst
+
+	// One creep still coming along the top, one just round the corner and
+	// one further down towards the base
+	coming := NewSmallCreep(g)
+	coming.Coords = corner.Sub(image.Pt(2, 0))
+	coming.NextWaypoint = 1
+	round := NewSmallCreep(g)
+	round.Coords = corner.Add(image.Pt(0, 2))
+	round.NextWaypoint = 2
+	ahead := NewSmallCreep(g)
+	ahead.Coords = corner.Add(image.Pt(0, 8))
+	ahead.NextWaypoint = 2
+	g.Creeps = Creeps{coming, round, ahead}
+
+	tower.findNewTarget(g)
+	if tower.Target != ahead {
+		t.Errorf("targeted the creep at %v, want the one closest to the base at %v", tower.Target.Coords, ahead.Coords)
+	}
+}
+
+func TestChainLightning(t *testing.T) {
+	g := newTestGame()
+	tower := NewChainTower(g)
+	tower.Coords = image.Pt(20, 30)
+
+	// A line of creeps each close enough to jump to from the one before,
+	// more of them than the chain can reach
+	for i := 0; i < ChainBounces+3; i++ {
+		c := NewBossCreep(g)
+		c.Coords = image.Pt(20+i*(ChainRadius-2), 30)
+		g.Creeps = append(g.Creeps, c)
+	}
+	tower.Target = g.Creeps[0] // Start at one end so the chain runs one way
+	tower.Locked = true
+	tower.Update(g)
+
+	if len(tower.Chain) != ChainBounces+1 {
+		t.Fatalf("chain hit %d creeps, want %d", len(tower.Chain), ChainBounces+1)
+	}
+	damage := tower.Stats().Damage
+	for i, c := range tower.Chain {
+		if lost := c.MaxHealth - c.Health; lost != damage {
+			t.Errorf("creep %d in the chain lost %d health, want %d", i, lost, damage)
+		}
+		damage = damage * ChainDecayPercent / 100
+	}
+	for _, c := range g.Creeps[ChainBounces+1:] {
+		if c.Health != c.MaxHealth {
+			t.Errorf("creep at %v past the end of the chain lost health", c.Coords)
+		}
+	}
+}
+
+func TestShotsPerSecond(t *testing.T) {
+	tests := []struct {
+		fireRate int
+		want     int
+	}{
+		{1, 60},
+		{20, 3},
+		{30, 2},
+		{60, 1},
+	}
+	for _, tt := range tests {
+		g := newTestGame()
+		tower := NewBasicTower(g)
+		tower.Coords = image.Pt(30, 30)
+		tower.FireRate = tt.fireRate
+		c := NewBossCreep(g)
+		c.Health = 1 << 30
+		c.Coords = tower.Coords
+		g.Creeps = Creeps{c}
+
+		shots := 0
+		for i := 0; i < LogicTPS; i++ {
+			tower.Update(g)
+			if tower.ShotTimer == shotDuration {
+				shots++
+			}
+		}
+		if shots != tt.want {
+			t.Errorf("tower with fire rate %d fired %d shots in a second, want %d", tt.fireRate, shots, tt.want)
+		}
+	}
+}
+
+func TestLockedTowerIgnoresCloserCreeps(t *testing.T) {
+	g := newTestGame()
+	tower := NewBasicTower(g)
+	tower.Coords = image.Pt(30, 30)
+	tower.TargetMode = targetModeClosest
+	near := NewBossCreep(g)
+	near.Coords = tower.Coords
+	far := NewBossCreep(g)
+	far.Coords = tower.Coords.Add(image.Pt(tower.Stats().Range, 0))
+	g.Creeps = Creeps{near, far}
+	g.Towers = Towers{tower}
+
+	// Left unlocked, a tower turns to the closest creep when it next shoots
+	tower.Target = far
+	tower.Update(g)
+	if tower.Target != near {
+		t.Fatalf("unlocked tower targeted the creep at %v, want the closest one", tower.Target.Coords)
+	}
+	tower.CycleLock(g) // Lock on to the current target
+	tower.CycleLock(g) // Move the lock on to the next creep
+	if tower.Target != far || !tower.Locked {
+		t.Fatalf("tower locked on to the creep at %v (locked %v), want the far one", tower.Target.Coords, tower.Locked)
+	}
+
+	health := near.Health
+	for i := 0; i < 60; i++ {
+		tower.Update(g)
+	}
+	if tower.Target != far || !tower.Locked {
+		t.Errorf("locked tower switched to the creep at %v, want it to stay on the far one", tower.Target.Coords)
+	}
+	if near.Health != health {
+		t.Errorf("locked tower hurt the closer creep")
+	}
+}
+
+func TestTowerRecordsDamageAndKills(t *testing.T) {
+	g := newTestGame()
+	tower := NewBasicTower(g)
+	tower.Coords = image.Pt(30, 30)
+	damage := tower.Stats().Damage
+	tough := NewBossCreep(g)
+	tough.Coords = tower.Coords
+	weak := NewTinyCreep(g)
+	weak.Health = damage
+	weak.Coords = tower.Coords
+	g.Creeps = Creeps{tough}
+
+	tower.Update(g)
+	tower.Cooldown = 0
+	tower.Update(g)
+	if tower.Dealt != 2*damage || tower.Kills != 0 {
+		t.Errorf("after two hits dealt %d damage and %d kills, want %d and 0", tower.Dealt, tower.Kills, 2*damage)
+	}
+
+	tower.Target = weak
+	g.Creeps = Creeps{weak}
+	tower.Cooldown = 0
+	tower.Update(g)
+	if tower.Dealt != 3*damage || tower.Kills != 1 {
+		t.Errorf("after a killing hit dealt %d damage and %d kills, want %d and 1", tower.Dealt, tower.Kills, 3*damage)
+	}
+
+	tower.Cooldown = 0
+	weak.Health = 0
+	tower.Target = weak
+	tower.Update(g)
+	if tower.Kills != 1 {
+		t.Errorf("credited %d kills after its target was already dead, want 1", tower.Kills)
+	}
+}
+
+func TestBigCreepEasierToReach(t *testing.T) {
+	g := newTestGame()
+	tower := NewBasicTower(g)
+	tower.Coords = image.Pt(30, 30)
+	at := image.Pt(30+tower.Stats().Range+3, 30)
+
+	tests := []struct {
+		name  string
+		creep *Creep
+		want  bool
+	}{
+		{"tiny", NewTinyCreep(g), false},
+		{"big", NewBigCreep(g), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tower.Target = nil
+			tt.creep.Coords = at
+			g.Creeps = Creeps{tt.creep}
+			tower.findNewTarget(g)
+			if got := tower.Target == tt.creep; got != tt.want {
+				t.Errorf("acquired %s creep at %v = %v, want %v", tt.name, at, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCloakedCreepRevealed(t *testing.T) {
+	tests := []struct {
+		name     string
+		offset   int
+		detector bool
+		want     bool
+	}{
+		{"in range but hidden", RevealRadius + 3, false, false},
+		{"close enough to see", RevealRadius - 1, false, true},
+		{"revealed by a detector", RevealRadius + 3, true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := newTestGame()
+			tower := NewBasicTower(g)
+			tower.Coords = image.Pt(30, 30)
+			g.Towers = Towers{tower}
+			if tt.detector {
+				detector := NewDetectorTower(g)
+				detector.Coords = image.Pt(30+tt.offset, 37)
+				g.Towers = append(g.Towers, detector)
+			}
+			c := NewStealthCreep(g)
+			c.Coords = image.Pt(30+tt.offset, 30)
+			g.Creeps = Creeps{c}
+
+			if !inRange(tower, c) {
+				t.Fatalf("creep at %v out of the tower's range", c.Coords)
+			}
+			tower.findNewTarget(g)
+			if got := tower.Target == c; got != tt.want {
+				t.Errorf("acquired cloaked creep %d away = %v, want %v", tt.offset, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBankIncome(t *testing.T) {
+	g := newTestGame()
+	bank := NewBankTower(g)
+	start := g.Money
+
+	for i := 0; i < bank.FireRate-1; i++ {
+		bank.Update(g)
+	}
+	if g.Money != start {
+		t.Fatalf("made %d before the first payout was due, want 0", g.Money-start)
+	}
+	bank.Update(g)
+	if got := g.Money - start; got != bank.Income {
+		t.Fatalf("made %d on the first payout, want %d", got, bank.Income)
+	}
+	for i := 0; i < bank.FireRate; i++ {
+		bank.Update(g)
+	}
+	if got := g.Money - start; got != bank.Income*2 {
+		t.Errorf("made %d after two payouts, want %d", got, bank.Income*2)
+	}
+
+	for i := 0; i < bank.FireRate*BankIncomeCap; i++ {
+		bank.Update(g)
+	}
+	if got := g.Money - start; got != BankIncomeCap {
+		t.Errorf("made %d in a whole round, want it capped at %d", got, BankIncomeCap)
+	}
+}
@@ -0,0 +1,114 @@
+// Copyright 2022 Siôn le Roux.  All rights reserved.
+// Use of this source code is subject to an MIT-style
+// licence which can be found in the LICENSE file.
+
+package main
+
+import (
+	"image"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/hajimehoshi/ebiten/v2/text"
+	"golang.org/x/image/font"
+)
+
+// TowerAction is one line of the menu for the tower under the cursor
+type TowerAction struct {
+	Name func(g *Game, t *Tower) string // What the line says for the tower
+	Do   func(g *Game, k int)           // Do it to the tower with the given index
+}
+
+// The things you can do to a tower from its menu, the same as their keys
+var towerActions = []TowerAction{
+	{
+		Name: func(g *Game, t *Tower) string { return g.T("UPGRADE") },
+		Do:   func(g *Game, k int) { g.buildAtCursor() },
+	},
+	{
+		Name: func(g *Game, t *Tower) string { return g.T("SELL") },
+		Do:   func(g *Game, k int) { g.sellTower(k) },
+	},
+	{
+		Name: func(g *Game, t *Tower) string { return g.T(t.TargetMode.String()) },
+		Do:   func(g *Game, k int) { g.cycleTargetMode(g.Towers[k]) },
+	},
+}
+
+// Open the menu for the tower under the cursor, if there is one
+func (g *Game) openTowerMenu() {
+	if IsOccupied(g, g.Cursor.Coords) == -1 {
+		return
+	}
+	g.TowerMenu = true
+	g.TowerMenuIndex = 0
+}
+
+// Handle input on the tower menu, W and S choose an action, X does it and
+// closes the menu and Enter closes it without doing anything
+func (g *Game) updateTowerMenu() {
+	k := IsOccupied(g, g.Cursor.Coords)
+	if k == -1 || inpututil.IsKeyJustPressed(ebiten.KeyEnter) {
+		g.TowerMenu = false // The tower could have been sold or undone
+		return
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyW) {
+		g.moveTowerMenu(-1)
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyS) {
+		g.moveTowerMenu(1)
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyX) {
+		g.chooseTowerAction(k)
+	}
+}
+
+// Move the choice in the tower menu up or down by some lines, going round to
+// the other end past the first or last one
+func (g *Game) moveTowerMenu(step int) {
+	n := len(towerActions)
+	g.TowerMenuIndex = ((g.TowerMenuIndex+step)%n + n) % n
+}
+
+// Do the chosen action to the tower with the given index and close the menu
+func (g *Game) chooseTowerAction(k int) {
+	towerActions[g.TowerMenuIndex].Do(g, k)
+	g.TowerMenu = false
+}
+
+// Draw the tower menu in a box next to the cursor, on whichever side of it
+// there's room, with the chosen action marked
+func (g *Game) drawTowerMenu(screen *ebiten.Image) {
+	k := IsOccupied(g, g.Cursor.Coords)
+	if !g.TowerMenu || k == -1 {
+		return
+	}
+	t := g.Towers[k]
+	lineHeight := 6
+	var lines []string
+	width := 0
+	for i, a := range towerActions {
+		txt := a.Name(g, t)
+		if i == g.TowerMenuIndex {
+			txt = ">" + txt
+		}
+		bounds, _ := font.BoundString(g.Font, txt)
+		width = max(width, (bounds.Max.X - bounds.Min.X).Ceil())
+		lines = append(lines, txt)
+	}
+	size := image.Pt(width+2*hudPadding, len(lines)*lineHeight+1)
+
+	pos := g.ScreenCoords(g.Cursor.Coords)
+	corner := image.Pt(pos.X+5, pos.Y-size.Y/2)
+	if corner.X+size.X > g.Size.X {
+		corner.X = pos.X - 5 - size.X
+	}
+	corner.X = max(0, corner.X)
+	corner.Y = max(hudHeight, min(g.Size.Y-size.Y, corner.Y))
+
+	ebitenutil.DrawRect(screen, float64(corner.X), float64(corner.Y), float64(size.X), float64(size.Y), ColorDark)
+	for i, txt := range lines {
+		text.Draw(screen, txt, g.Font, corner.X+hudPadding, corner.Y+lineHeight*(i+1), ColorLight)
+	}
+}
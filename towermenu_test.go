@@ -0,0 +1,62 @@
+// Copyright 2022 Siôn le Roux.  All rights reserved.
+// Use of this source code is subject to an MIT-style
+// licence which can be found in the LICENSE file.
+
+package main
+
+import "testing"
+
+func TestTowerMenu(t *testing.T) {
+	tests := []struct {
+		name  string
+		moves []int
+		index int
+		check func(g *Game) bool
+	}{
+		{"upgrade", nil, 0, func(g *Game) bool {
+			return len(g.Towers) == 1 && g.Towers[0].Kind == towerKindStrong
+		}},
+		{"sell", []int{1}, 1, func(g *Game) bool {
+			return len(g.Towers) == 0
+		}},
+		{"target mode going up past the top", []int{-1}, 2, func(g *Game) bool {
+			return len(g.Towers) == 1 && g.Towers[0].TargetMode != targetModeFirst
+		}},
+		{"going down past the bottom", []int{1, 1, 1}, 0, func(g *Game) bool {
+			return len(g.Towers) == 1 && g.Towers[0].Kind == towerKindStrong
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := newTestGame()
+			g.Cursor.Coords = tileCoords(2, 2)
+			placeTower(g, towerKindBasic, 2, 2)
+			g.Towers[0].TargetMode = targetModeFirst
+
+			g.openTowerMenu()
+			if !g.TowerMenu || g.TowerMenuIndex != 0 {
+				t.Fatalf("menu open %v on line %d, want open on the first line", g.TowerMenu, g.TowerMenuIndex)
+			}
+			for _, step := range tt.moves {
+				g.moveTowerMenu(step)
+			}
+			if g.TowerMenuIndex != tt.index {
+				t.Fatalf("moving %v chose line %d, want %d", tt.moves, g.TowerMenuIndex, tt.index)
+			}
+			g.chooseTowerAction(0)
+			if g.TowerMenu {
+				t.Error("menu still open after choosing an action")
+			}
+			if !tt.check(g) {
+				t.Errorf("choosing line %d didn't do its action to the tower", tt.index)
+			}
+		})
+	}
+
+	g := newTestGame()
+	g.Cursor.Coords = tileCoords(2, 2)
+	g.openTowerMenu()
+	if g.TowerMenu {
+		t.Error("menu opened without a tower under the cursor")
+	}
+}
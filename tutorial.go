@@ -0,0 +1,71 @@
+// Copyright 2022 Siôn le Roux.  All rights reserved.
+// Use of this source code is subject to an MIT-style
+// licence which can be found in the LICENSE file.
+
+package main
+
+import (
+	"log"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+// TutorialHint is one of the hints shown the first time the game is played,
+// until you do what it says
+type TutorialHint struct {
+	Text string             // What to do, short enough for the bottom bar
+	Done func(g *Game) bool // Whether you've done it
+}
+
+// The hints shown in order the first time the game is played
+var tutorialHints = []TutorialHint{
+	{
+		Text: "WASD TO MOVE",
+		Done: func(g *Game) bool {
+			for _, k := range []ebiten.Key{ebiten.KeyW, ebiten.KeyA, ebiten.KeyS, ebiten.KeyD} {
+				if inpututil.IsKeyJustPressed(k) {
+					return true
+				}
+			}
+			return false
+		},
+	},
+	{
+		Text: "X TO BUILD",
+		Done: func(g *Game) bool { return len(g.Towers) > 0 },
+	},
+	{
+		Text: "Q TO SELL",
+		Done: func(g *Game) bool { return inpututil.IsKeyJustPressed(ebiten.KeyQ) },
+	},
+}
+
+// Move on to the next hint once you've done what the current one says, and
+// remember that the tutorial is over after the last one so it isn't shown again
+func (g *Game) updateTutorial() {
+	if g.Settings.TutorialDone || !tutorialHints[g.TutorialStep].Done(g) {
+		return
+	}
+	g.TutorialStep++
+	if g.TutorialStep < len(tutorialHints) {
+		return
+	}
+	g.TutorialStep = 0
+	g.Settings.TutorialDone = true
+	if err := g.Settings.Save(); err != nil {
+		log.Println("Saving settings failed:", err)
+	}
+}
+
+// Draw the current hint in a bar along the bottom of the screen while you're
+// playing, until the tutorial is over
+func (g *Game) drawTutorial(screen *ebiten.Image) {
+	if g.Settings.TutorialDone || g.State != gameStateBuild {
+		return
+	}
+	top := g.Size.Y - hudHeight
+	ebitenutil.DrawRect(screen, 0, float64(top), float64(g.Size.X), hudHeight, ColorDark)
+	g.drawBarText(screen, g.T(tutorialHints[g.TutorialStep].Text), hudAlignCenter, top+hudBaseline)
+}
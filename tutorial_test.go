@@ -0,0 +1,51 @@
+// Copyright 2022 Siôn le Roux.  All rights reserved.
+// Use of this source code is subject to an MIT-style
+// licence which can be found in the LICENSE file.
+
+package main
+
+import "testing"
+
+func TestTutorialAdvances(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	g := newTestGame()
+	g.TutorialStep = 1 // X TO BUILD
+
+	g.updateTutorial()
+	if g.TutorialStep != 1 {
+		t.Fatalf("on hint %d before building anything, want still on 1", g.TutorialStep)
+	}
+	placeTower(g, towerKindBasic, 2, 2)
+	g.updateTutorial()
+	if g.TutorialStep != 2 {
+		t.Errorf("on hint %d after building, want the next one", g.TutorialStep)
+	}
+
+	// Follow every hint of a made-up tutorial to see it's put away for good
+	hints := tutorialHints
+	t.Cleanup(func() { tutorialHints = hints })
+	done := false
+	tutorialHints = []TutorialHint{
+		{Text: "FIRST", Done: func(g *Game) bool { return true }},
+		{Text: "LAST", Done: func(g *Game) bool { return done }},
+	}
+	g.TutorialStep = 0
+	g.updateTutorial()
+	g.updateTutorial()
+	if g.TutorialStep != 1 || g.Settings.TutorialDone {
+		t.Fatalf("on hint %d with the tutorial done %v, want waiting on the last hint",
+			g.TutorialStep, g.Settings.TutorialDone)
+	}
+	done = true
+	g.updateTutorial()
+	if !g.Settings.TutorialDone {
+		t.Errorf("tutorial not done after following the last hint")
+	}
+	loaded, err := LoadSettings()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !loaded.TutorialDone {
+		t.Errorf("finishing the tutorial wasn't saved in the settings")
+	}
+}
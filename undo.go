@@ -0,0 +1,61 @@
+// Copyright 2022 Siôn le Roux.  All rights reserved.
+// Use of this source code is subject to an MIT-style
+// licence which can be found in the LICENSE file.
+
+package main
+
+import (
+	"log"
+	"slices"
+)
+
+// How many tower placements can be undone at most
+const UndoHistory = 5
+
+// Placement is a tower that was just built or upgraded, and the tower it
+// replaced if it was an upgrade
+type Placement struct {
+	Tower    *Tower
+	Replaced *Tower
+}
+
+// Remember a tower placement so that it can be undone
+func (g *Game) recordPlacement(t, replaced *Tower) {
+	g.Placements = append(g.Placements, Placement{Tower: t, Replaced: replaced})
+	if len(g.Placements) > UndoHistory {
+		g.Placements = g.Placements[1:]
+	}
+}
+
+// Forget tower placements once they can't be undone any more, because creeps
+// have been hurt since they were made
+func (g *Game) forgetPlacements() {
+	g.Placements = nil
+}
+
+// CanUndo says whether there's a tower placement that can be undone
+func (g *Game) CanUndo() bool {
+	return len(g.Placements) > 0
+}
+
+// Undo takes back the last tower placement, refunding its full cost and
+// putting back the tower it replaced if it was an upgrade
+func (g *Game) Undo() {
+	if !g.CanUndo() {
+		return
+	}
+	p := g.Placements[len(g.Placements)-1]
+	g.Placements = g.Placements[:len(g.Placements)-1]
+	k := slices.Index(g.Towers, p.Tower)
+	if k == -1 {
+		return
+	}
+	if p.Replaced != nil {
+		g.Towers[k] = p.Replaced
+	} else {
+		g.Towers = append(g.Towers[:k], g.Towers[k+1:]...)
+	}
+	g.Money += p.Tower.Cost
+	g.TotalSpent -= p.Tower.Cost
+	log.Printf("Undid tower placement, refunded %d\n", p.Tower.Cost)
+}
@@ -0,0 +1,56 @@
+// Copyright 2022 Siôn le Roux.  All rights reserved.
+// Use of this source code is subject to an MIT-style
+// licence which can be found in the LICENSE file.
+
+package main
+
+import "testing"
+
+func TestUndoRefunds(t *testing.T) {
+	g := newTestGame()
+	g.Cursor.Coords = tileCoords(2, 2)
+	money := g.Money
+
+	if got := buyTowerAt(g, g.Cursor.Coords); got != buyBuilt {
+		t.Fatalf("building = %v, want %v", got, buyBuilt)
+	}
+	built := g.Money
+	if got := buyTowerAt(g, g.Cursor.Coords); got != buyUpgraded {
+		t.Fatalf("upgrading = %v, want %v", got, buyUpgraded)
+	}
+
+	g.Undo()
+	if g.Money != built || g.Towers[0].Kind != towerKindBasic {
+		t.Errorf("undoing the upgrade left %d money and a %v tower, want %d and a %v tower",
+			g.Money, g.Towers[0].Kind, built, towerKindBasic)
+	}
+	g.Undo()
+	if g.Money != money || len(g.Towers) != 0 || g.TotalSpent != 0 {
+		t.Errorf("undoing the build left %d money, %d towers and %d spent, want %d, 0 and 0",
+			g.Money, len(g.Towers), g.TotalSpent, money)
+	}
+}
+
+func TestUndoRefusedAfterDamage(t *testing.T) {
+	g := newTestGame()
+	g.Cursor.Coords = tileCoords(2, 2)
+	buyTowerAt(g, g.Cursor.Coords)
+	tower := g.Towers[0]
+	c := NewSmallCreep(g)
+	c.Coords = tower.Coords
+	g.Creeps = Creeps{c}
+
+	tower.Update(g)
+	if tower.Dealt == 0 {
+		t.Fatal("tower didn't hurt the creep next to it")
+	}
+	money := g.Money
+	if g.CanUndo() {
+		t.Error("CanUndo() = true after the tower dealt damage, want false")
+	}
+	g.Undo()
+	if g.Money != money || len(g.Towers) != 1 {
+		t.Errorf("undo after dealing damage left %d money and %d towers, want %d and 1",
+			g.Money, len(g.Towers), money)
+	}
+}
@@ -0,0 +1,100 @@
+// Copyright 2022 Siôn le Roux.  All rights reserved.
+// Use of this source code is subject to an MIT-style
+// licence which can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"log"
+)
+
+// UpgradeTrack is one of the stats of a tower that can be upgraded on its own
+type UpgradeTrack int
+
+const (
+	trackDamage UpgradeTrack = iota
+	trackRange
+	trackFireRate
+	trackCount
+)
+
+// String is the short name of the upgrade track shown in the HUD
+func (tr UpgradeTrack) String() string {
+	switch tr {
+	case trackRange:
+		return "RNG"
+	case trackFireRate:
+		return "SPD"
+	default:
+		return "DMG"
+	}
+}
+
+// Upgrade tracks and their effect on a tower's stats
+const (
+	MaxTrackLevel       = 3  // How many times each track can be upgraded
+	TrackCostPercent    = 50 // Cost of each level as a percentage of the tower's cost
+	DamagePerLevel      = 25 // Extra damage for each damage level, in percent
+	RangePerLevel       = 3  // Extra pixels of range for each range level
+	FireRatePerLevel    = 15 // Less time between shots for each fire rate level, in percent
+	baseTowerRangeTiles = 2  // Range of a tower without upgrades, in tiles
+)
+
+// TowerStats are the stats a tower actually uses after its upgrades
+type TowerStats struct {
+	Damage   int // Damage dealt by each shot
+	Range    int // How far away from the tower a creep can be hit, in pixels
+	FireRate int // Ticks between shots
+}
+
+// Work out a tower's stats from its base damage and fire rate and the levels
+// it has on each upgrade track
+func towerStats(damage, fireRate int, levels [trackCount]int) TowerStats {
+	tileSize := 7
+	return TowerStats{
+		Damage:   damage + damage*levels[trackDamage]*DamagePerLevel/100,
+		Range:    baseTowerRangeTiles*tileSize + levels[trackRange]*RangePerLevel,
+		FireRate: max(1, fireRate-fireRate*levels[trackFireRate]*FireRatePerLevel/100),
+	}
+}
+
+// Stats are the tower's stats with its upgrades
+func (t *Tower) Stats() TowerStats {
+	return towerStats(t.Damage, t.FireRate, t.Levels)
+}
+
+// TrackCost is how much the next level of an upgrade track costs, or -1 if the
+// track is fully upgraded
+func (t *Tower) TrackCost(tr UpgradeTrack) int {
+	level := t.Levels[tr]
+	if level >= MaxTrackLevel {
+		return -1
+	}
+	return t.Cost * TrackCostPercent / 100 * (level + 1)
+}
+
+// BuyTrackUpgrade upgrades one stat of the tower under the cursor if you can
+// afford it
+func BuyTrackUpgrade(g *Game, tr UpgradeTrack) {
+	k := IsOccupied(g, g.Cursor.Coords)
+	if k == -1 {
+		return
+	}
+	t := g.Towers[k]
+	cost := t.TrackCost(tr)
+	if cost < 0 {
+		log.Println("Upgrade track already maxed out")
+		return
+	}
+	if g.Money < cost {
+		log.Println("Not enough money for upgrade")
+		return
+	}
+	g.Money -= cost
+	g.TotalSpent += cost
+	t.Invested += cost
+	t.Levels[tr]++
+	g.forgetPlacements()
+	g.ShowNotice(fmt.Sprintf("%s%d", tr, t.Levels[tr]))
+}
@@ -0,0 +1,54 @@
+// Copyright 2022 Siôn le Roux.  All rights reserved.
+// Use of this source code is subject to an MIT-style
+// licence which can be found in the LICENSE file.
+
+package main
+
+import "testing"
+
+func TestTowerStatsMixedLevels(t *testing.T) {
+	tileSize := 7
+	tests := []struct {
+		name   string
+		levels [trackCount]int
+		want   TowerStats
+	}{
+		{"no upgrades", [trackCount]int{0, 0, 0}, TowerStats{100, baseTowerRangeTiles * tileSize, 20}},
+		{"damage only", [trackCount]int{2, 0, 0}, TowerStats{150, baseTowerRangeTiles * tileSize, 20}},
+		{"range and speed", [trackCount]int{0, 1, 3}, TowerStats{100, baseTowerRangeTiles*tileSize + 3, 11}},
+		{"every track", [trackCount]int{3, 3, 3}, TowerStats{175, baseTowerRangeTiles*tileSize + 9, 11}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := towerStats(100, 20, tt.levels); got != tt.want {
+				t.Errorf("towerStats(100, 20, %v) = %+v, want %+v", tt.levels, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpgradeKeepsState(t *testing.T) {
+	g := newTestGame()
+	target := NewSmallCreep(g)
+	tower := NewBasicTower(g)
+	tower.Invested = 300
+	tower.Earned = 40
+	tower.Levels = [trackCount]int{1, 2, 0}
+	tower.Target = target
+	tower.Locked = true
+	tower.TargetMode = targetModeClosest
+	tower.Facing = 2
+	tower.Dealt = 500
+	tower.Kills = 3
+
+	tu := tower.Upgrade(g)
+	if tu.Kind != towerKindStrong {
+		t.Fatalf("upgraded into %v, want %v", tu.Kind, towerKindStrong)
+	}
+	if tu.Coords != tower.Coords || tu.Invested != tower.Invested ||
+		tu.Earned != tower.Earned || tu.Levels != tower.Levels ||
+		tu.Target != target || !tu.Locked || tu.TargetMode != targetModeClosest ||
+		tu.Facing != tower.Facing || tu.Dealt != tower.Dealt || tu.Kills != tower.Kills {
+		t.Errorf("upgraded tower %+v lost the state of %+v", tu, tower)
+	}
+}
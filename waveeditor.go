@@ -0,0 +1,131 @@
+// Copyright 2022 Siôn le Roux.  All rights reserved.
+// Use of this source code is subject to an MIT-style
+// licence which can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/hajimehoshi/ebiten/v2/text"
+)
+
+// Names of the kinds of creep in the order the number keys add them
+var editorCreeps = slices.Sorted(maps.Keys(creepKinds))
+
+// Number keys for adding each kind of creep in the wave editor
+var editorKeys = []ebiten.Key{
+	ebiten.Key1, ebiten.Key2, ebiten.Key3, ebiten.Key4, ebiten.Key5,
+	ebiten.Key6, ebiten.Key7, ebiten.Key8, ebiten.Key9,
+}
+
+// Data for the map being played, which the wave editor changes
+func (g *Game) mapData() *MapData {
+	if g.MapIndex == 1 {
+		return &g.MapData2
+	}
+	return &g.MapData1
+}
+
+// Where the map's data file is in the source tree, so edited waves are saved
+// over the file that gets embedded in the next build
+func mapFile(index int) string {
+	return fmt.Sprintf("assets/maps/map%d.json", index+1)
+}
+
+// Handle input in the wave editor, W and S choose a part of the wave, the
+// number keys add a part with that kind of creep after it, A and D change how
+// many creeps it sends, backspace removes it and enter saves the map file,
+// changes are played from the next round and F1 goes back to the debug menu
+func (g *Game) updateWaveEditor() {
+	data := g.mapData()
+	wave := data.Wave
+	if inpututil.IsKeyJustPressed(ebiten.KeyF1) {
+		g.State = gameStateDebug
+		g.OptionIndex = 0
+		return
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyW) && g.OptionIndex > 0 {
+		g.OptionIndex--
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyS) && g.OptionIndex < len(wave)-1 {
+		g.OptionIndex++
+	}
+	for i, k := range editorKeys[:min(len(editorKeys), len(editorCreeps))] {
+		if inpututil.IsKeyJustPressed(k) {
+			at := min(len(wave), g.OptionIndex+1)
+			segment := WaveSegment{Creep: editorCreeps[i], Count: 1, Interval: SpawnInterval}
+			wave = slices.Insert(wave, at, segment)
+			g.OptionIndex = at
+		}
+	}
+	if g.OptionIndex < len(wave) {
+		s := &wave[g.OptionIndex]
+		if inpututil.IsKeyJustPressed(ebiten.KeyA) && s.Count > 1 {
+			s.Count--
+		}
+		if inpututil.IsKeyJustPressed(ebiten.KeyD) {
+			s.Count++
+		}
+		if inpututil.IsKeyJustPressed(ebiten.KeyBackspace) {
+			wave = slices.Delete(wave, g.OptionIndex, g.OptionIndex+1)
+			g.OptionIndex = max(0, min(g.OptionIndex, len(wave)-1))
+		}
+	}
+	data.Wave = wave
+	if inpututil.IsKeyJustPressed(ebiten.KeyEnter) {
+		if err := saveMapData(mapFile(g.MapIndex), *data); err != nil {
+			log.Println("Saving wave failed:", err)
+			g.ShowNotice(g.T("FAILED"))
+		} else {
+			g.ShowNotice(g.T("SAVED"))
+		}
+	}
+	if g.NoticeTimer > 0 {
+		g.NoticeTimer--
+	}
+}
+
+// Write map data back to its JSON file
+func saveMapData(name string, data MapData) error {
+	out, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding map %s: %w", name, err)
+	}
+	if err := os.WriteFile(name, out, 0644); err != nil {
+		return fmt.Errorf("writing map %s: %w", name, err)
+	}
+	return nil
+}
+
+// Draw the wave editor, a scrolling list of the parts of the wave with the
+// chosen one marked
+func (g *Game) drawWaveEditor(screen *ebiten.Image) {
+	ebitenutil.DrawRect(screen, 0, 0, float64(g.Size.X), hudHeight, ColorDark)
+	title := fmt.Sprintf("WAVE %d", g.MapIndex+1)
+	if g.NoticeTimer > 0 {
+		title = g.Notice
+	}
+	g.drawHUDText(screen, title, hudAlignCenter)
+	lineHeight := g.textLineHeight()
+	lines := (g.Size.Y - hudHeight) / lineHeight
+	first := max(0, g.OptionIndex-lines+2)
+	wave := g.mapData().Wave
+	for i := first; i < min(len(wave), first+lines); i++ {
+		y := hudHeight + lineHeight*(i-first+1)
+		txt := fmt.Sprintf("%d %s", wave[i].Count, strings.ToUpper(wave[i].Creep))
+		if i == g.OptionIndex {
+			txt = ">" + txt
+		}
+		text.Draw(screen, txt, g.TextFont, hudPadding, y, ColorDark)
+	}
+}
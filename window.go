@@ -0,0 +1,54 @@
+// Copyright 2022 Siôn le Roux.  All rights reserved.
+// Use of this source code is subject to an MIT-style
+// licence which can be found in the LICENSE file.
+
+package main
+
+import (
+	"log"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+// How many times bigger than the game the window is, and how far it can be
+// zoomed in and out
+const (
+	DefaultWindowScale = 10
+	MinWindowScale     = 2
+	MaxWindowScale     = 20
+)
+
+// Keep a window scale between the smallest and biggest allowed, using the
+// default for one that was never set
+func clampWindowScale(scale int) int {
+	if scale == 0 {
+		return DefaultWindowScale
+	}
+	return max(MinWindowScale, min(MaxWindowScale, scale))
+}
+
+// SetWindowScale resizes the window to a new scale and remembers it for the
+// next time the game is run
+func (g *Game) SetWindowScale(scale int) {
+	scale = clampWindowScale(scale)
+	ebiten.SetWindowSize(g.Size.X*scale, g.Size.Y*scale)
+	if scale == clampWindowScale(g.Settings.WindowScale) {
+		return
+	}
+	g.Settings.WindowScale = scale
+	if err := g.Settings.Save(); err != nil {
+		log.Println("Saving settings failed:", err)
+	}
+}
+
+// Zoom the window in and out with + and -
+func (g *Game) updateZoom() {
+	scale := clampWindowScale(g.Settings.WindowScale)
+	if inpututil.IsKeyJustPressed(ebiten.KeyEqual) || inpututil.IsKeyJustPressed(ebiten.KeyNumpadAdd) {
+		g.SetWindowScale(scale + 1)
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyMinus) || inpututil.IsKeyJustPressed(ebiten.KeyNumpadSubtract) {
+		g.SetWindowScale(scale - 1)
+	}
+}
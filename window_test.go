@@ -0,0 +1,30 @@
+// Copyright 2022 Siôn le Roux.  All rights reserved.
+// Use of this source code is subject to an MIT-style
+// licence which can be found in the LICENSE file.
+
+package main
+
+import "testing"
+
+func TestClampWindowScale(t *testing.T) {
+	tests := []struct {
+		name  string
+		scale int
+		want  int
+	}{
+		{"never set", 0, DefaultWindowScale},
+		{"below the minimum", MinWindowScale - 1, MinWindowScale},
+		{"negative", -5, MinWindowScale},
+		{"the minimum", MinWindowScale, MinWindowScale},
+		{"in between", 7, 7},
+		{"the maximum", MaxWindowScale, MaxWindowScale},
+		{"above the maximum", MaxWindowScale + 1, MaxWindowScale},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := clampWindowScale(tt.scale); got != tt.want {
+				t.Errorf("clampWindowScale(%d) = %d, want %d", tt.scale, got, tt.want)
+			}
+		})
+	}
+}